@@ -1,20 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/joho/godotenv"
-
-	common "gitlab.com/BobyMCbobs/go-http-server/pkg/common"
-	ghs "gitlab.com/BobyMCbobs/go-http-server/pkg/httpserver"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/cmd"
 )
 
 func main() {
-	log.Printf("%v (%v, %v, %v, %v)\n", common.AppName, common.AppBuildVersion, common.AppBuildHash, common.AppBuildMode, common.AppBuildDate)
-	if common.AppBuildMode == "development" {
-		_ = godotenv.Load(".env")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := cmd.Run(ctx, os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
 	}
-	ws := ghs.NewWebServer()
-	log.Printf("Configuration: %#v\n", ws)
-	ws.Listen()
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,11 +14,13 @@ import (
 	"testing"
 	"time"
 
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/cmd"
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
 )
 
 var defaultEnv = map[string]string{
-	"APP_METRICS_ENABLED": "false",
+	"APP_METRICS_ENABLED":     "false",
+	"APP_HEALTH_PORT_ENABLED": "false",
 }
 var client = &http.Client{
 	CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -732,7 +735,9 @@ redirectRoutes:
 				// NOTE not sure how to quit when the test finishes yet
 				//      so it will become a zombie go routine until `go test` completes
 				//      each test must bind to unique ports
-				main()
+				if err := cmd.Run(context.Background(), nil, io.Discard); err != nil {
+					log.Println(err)
+				}
 			}()
 			// wait for port listening
 			for {
@@ -0,0 +1,145 @@
+package adminserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config configures the server of an admin endpoint, such as metrics or health
+type Config struct {
+	// Name is the name of the endpoint in logs and errors
+	Name            string
+	Port            string
+	ShutdownTimeout time.Duration
+	// ConfigureServer sets the timeouts and limits of the server, in place of the defaults
+	ConfigureServer func(*http.Server)
+	Handler         http.Handler
+}
+
+// Server serves an admin endpoint on its own port. A new HTTP server is created
+// each time it serves, so that it may be started again after shutting down
+type Server struct {
+	mu     sync.Mutex
+	server *http.Server
+	// closed is set when shut down before serving, for the next serve to return
+	closed bool
+}
+
+// newHTTPServer returns the HTTP server for the config, with the default timeouts
+func newHTTPServer(c Config) *http.Server {
+	server := &http.Server{
+		Handler:           c.Handler,
+		Addr:              c.Port,
+		WriteTimeout:      15 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if c.ConfigureServer != nil {
+		c.ConfigureServer(server)
+	}
+	return server
+}
+
+// Serve ...
+// serves on the listener until shut down
+func (s *Server) Serve(c Config, l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.closed = false
+		s.mu.Unlock()
+		return l.Close()
+	}
+	server := newHTTPServer(c)
+	s.server = server
+	s.mu.Unlock()
+
+	err := server.Serve(l)
+	s.mu.Lock()
+	if s.server == server {
+		s.server = nil
+	}
+	s.mu.Unlock()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown ...
+// gracefully stops serving. When not yet serving, the next serve returns straight away
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.server
+	s.closed = server == nil
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Start ...
+// serves on the port until the context is cancelled
+func (s *Server) Start(ctx context.Context, c Config) error {
+	l, err := net.Listen("tcp", c.Port)
+	if err != nil {
+		return fmt.Errorf("failed to listen for %v on %v: %w", c.Name, c.Port, err)
+	}
+	slog.Info("listening", "server", c.Name, "addr", l.Addr().String())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Serve(c, l)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	timeout := c.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return <-errs
+}
+
+// Handle ...
+// serves on the port until interrupted, or until true is received on the channel,
+// exiting on errors. When a channel is provided, the caller is responsible for stopping
+func (s *Server) Handle(c Config, ch ...<-chan bool) {
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if len(ch) == 0 {
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	} else {
+		go func() {
+			for {
+				c, ok := <-ch[0]
+				if !ok {
+					return
+				}
+				if c {
+					stop()
+				}
+			}
+		}()
+	}
+	if err := s.Start(ctx, c); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+package adminserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().String()
+	l.Close()
+	return Config{
+		Name: "test",
+		Port: port,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ok")
+		}),
+	}
+}
+
+// waitForServer gets from the server until it responds
+func waitForServer(t *testing.T, port string) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		resp, err := http.Get("http://" + port)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "ok" {
+				t.Fatalf("GET %v = %q, want ok", port, body)
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("server on %v didn't respond", port)
+}
+
+func TestServer_Start_restart(t *testing.T) {
+	c := testConfig(t)
+	s := &Server{}
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.Start(ctx, c)
+		}()
+		waitForServer(t, c.Port)
+		cancel()
+		if err := <-errs; err != nil {
+			t.Fatalf("Server.Start() run %v error = %v", i, err)
+		}
+	}
+}
+
+func TestServer_Shutdown_beforeServe(t *testing.T) {
+	c := testConfig(t)
+	s := &Server{}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Server.Shutdown() error = %v", err)
+	}
+	l, err := net.Listen("tcp", c.Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(c, l)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Server.Serve() after Shutdown() didn't return")
+	}
+	// the next serve isn't affected
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Start(ctx, c)
+	}()
+	waitForServer(t, c.Port)
+	cancel()
+	if err := <-errs; err != nil {
+		t.Fatalf("Server.Start() error = %v", err)
+	}
+}
+
+func TestServer_Handle(t *testing.T) {
+	c := testConfig(t)
+	s := &Server{}
+	ch := make(chan bool)
+	done := make(chan struct{})
+	go func() {
+		s.Handle(c, ch)
+		close(done)
+	}()
+	waitForServer(t, c.Port)
+	ch <- true
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Server.Handle() didn't return when stopped")
+	}
+}
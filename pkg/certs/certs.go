@@ -0,0 +1,134 @@
+package certs
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+)
+
+// Provider ...
+// serves a certificate and key from files, which are loaded again on Reload.
+// When the files fail to load, the last good certificate continues to be served
+type Provider struct {
+	certPath string
+	keyPath  string
+
+	cert atomic.Pointer[tls.Certificate]
+	mu   sync.Mutex
+	hash [sha256.Size]byte
+	err  error
+}
+
+// NewProvider ...
+// returns a provider of the certificate and key at the paths, loading them. The provider is
+// returned along with the error when they fail to load, for them to be loaded on a later reload
+func NewProvider(certPath string, keyPath string) (*Provider, error) {
+	p := &Provider{certPath: certPath, keyPath: keyPath}
+	return p, p.Reload()
+}
+
+// Reload ...
+// loads the certificate and key from the files, keeping the current certificate when they fail to load
+func (p *Provider) Reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	certPEM, keyPEM, hash, err := p.read()
+	p.hash = hash
+	if err == nil {
+		err = p.load(certPEM, keyPEM)
+	}
+	metrics.RecordCertificateReload(err)
+	p.err = err
+	return err
+}
+
+// read returns the content of the certificate and key files, and their hash
+func (p *Provider) read() (certPEM []byte, keyPEM []byte, hash [sha256.Size]byte, err error) {
+	certPEM, certErr := os.ReadFile(p.certPath)
+	keyPEM, keyErr := os.ReadFile(p.keyPath)
+	h := sha256.New()
+	h.Write(certPEM)
+	h.Write(keyPEM)
+	copy(hash[:], h.Sum(nil))
+	if certErr != nil {
+		return nil, nil, hash, certErr
+	}
+	if keyErr != nil {
+		return nil, nil, hash, keyErr
+	}
+	return certPEM, keyPEM, hash, nil
+}
+
+// load parses the certificate and key, storing them to be served
+func (p *Provider) load(certPEM []byte, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %v: %w", p.certPath, err)
+	}
+	if cert.Leaf == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate %v: %w", p.certPath, err)
+		}
+		cert.Leaf = leaf
+	}
+	p.cert.Store(&cert)
+	metrics.SetCertificateExpiry(p.certPath, cert.Leaf.NotAfter)
+	return nil
+}
+
+// changed returns if the content of the files differs from when they were last loaded
+func (p *Provider) changed() bool {
+	_, _, hash, _ := p.read()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return hash != p.hash
+}
+
+// reloadIfChanged reloads the certificate when the files have changed, logging the result
+func (p *Provider) reloadIfChanged() {
+	if !p.changed() {
+		return
+	}
+	if err := p.Reload(); err != nil {
+		slog.Error("failed to reload TLS certificate, keeping the current certificate", "path", p.certPath, "error", err)
+		return
+	}
+	slog.Info("reloaded TLS certificate", "path", p.certPath)
+}
+
+// Certificate ...
+// returns the certificate being served, or nil when none has loaded
+func (p *Provider) Certificate() *tls.Certificate {
+	return p.cert.Load()
+}
+
+// GetCertificate ...
+// returns the certificate to serve, for tls.Config.GetCertificate
+func (p *Provider) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := p.cert.Load(); cert != nil {
+		return cert, nil
+	}
+	return nil, p.Err()
+}
+
+// Err ...
+// returns the error loading the certificate, when no certificate has loaded
+func (p *Provider) Err() error {
+	if p.cert.Load() != nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		return fmt.Errorf("no certificate loaded from %v", p.certPath)
+	}
+	return p.err
+}
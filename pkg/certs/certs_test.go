@@ -0,0 +1,134 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for the DNS names, otherwise example.com,
+// expiring at notAfter
+func writeCertificate(t *testing.T, certPath string, keyPath string, notAfter time.Time, dnsNames ...string) {
+	t.Helper()
+	if len(dnsNames) == 0 {
+		dnsNames = []string{"example.com"}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProvider_Reload(t *testing.T) {
+	first := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	second := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name         string
+		update       func(t *testing.T, certPath string, keyPath string)
+		wantErr      bool
+		wantNotAfter time.Time
+	}{
+		{
+			name: "rotated",
+			update: func(t *testing.T, certPath string, keyPath string) {
+				writeCertificate(t, certPath, keyPath, second)
+			},
+			wantNotAfter: second,
+		},
+		{
+			name: "invalid keeps the last good certificate",
+			update: func(t *testing.T, certPath string, keyPath string) {
+				if err := os.WriteFile(certPath, []byte("not a certificate"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:      true,
+			wantNotAfter: first,
+		},
+		{
+			name: "removed keeps the last good certificate",
+			update: func(t *testing.T, certPath string, keyPath string) {
+				if err := os.Remove(keyPath); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:      true,
+			wantNotAfter: first,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			certPath, keyPath := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+			writeCertificate(t, certPath, keyPath, first)
+			p, err := NewProvider(certPath, keyPath)
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			tt.update(t, certPath, keyPath)
+			if err := p.Reload(); (err != nil) != tt.wantErr {
+				t.Fatalf("Provider.Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			cert, err := p.GetCertificate(nil)
+			if err != nil {
+				t.Fatalf("Provider.GetCertificate() error = %v", err)
+			}
+			if got := cert.Leaf.NotAfter; !got.Equal(tt.wantNotAfter) {
+				t.Errorf("Provider.GetCertificate() expiry = %v, want %v", got, tt.wantNotAfter)
+			}
+			if err := p.Err(); err != nil {
+				t.Errorf("Provider.Err() = %v, want nil with a certificate loaded", err)
+			}
+		})
+	}
+}
+
+func TestNewProvider_missing(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+	p, err := NewProvider(certPath, keyPath)
+	if err == nil {
+		t.Fatal("NewProvider() error = nil, want an error for missing files")
+	}
+	if _, err := p.GetCertificate(nil); err == nil {
+		t.Error("Provider.GetCertificate() error = nil, want an error without a certificate")
+	}
+	// the certificate is served once it is written
+	writeCertificate(t, certPath, keyPath, time.Now().Add(time.Hour))
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Provider.Reload() error = %v", err)
+	}
+	if p.Certificate() == nil || p.Err() != nil {
+		t.Errorf("Provider.Certificate() = %v, Err() = %v, want a certificate", p.Certificate(), p.Err())
+	}
+}
@@ -0,0 +1,24 @@
+package certs
+
+import (
+	"crypto/tls"
+)
+
+// ClientIdentity ...
+// returns the subject and subject alternative names of the verified client certificate
+// of the connection, if any. The names are DNS names, IPs, emails and then URIs
+func ClientIdentity(state *tls.ConnectionState) (subject string, sans []string, ok bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", nil, false
+	}
+	cert := state.VerifiedChains[0][0]
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return cert.Subject.String(), sans, true
+}
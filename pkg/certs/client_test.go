@@ -0,0 +1,53 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClientIdentity(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.com/dashboard")
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "dashboard", Organization: []string{"Example"}},
+		DNSNames:       []string{"dashboard.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("192.0.2.1")},
+		EmailAddresses: []string{"ops@example.com"},
+		URIs:           []*url.URL{spiffe},
+	}
+	tests := []struct {
+		name        string
+		state       *tls.ConnectionState
+		wantSubject string
+		wantSANs    []string
+		wantOK      bool
+	}{
+		{
+			name:        "verified",
+			state:       &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+			wantSubject: "CN=dashboard,O=Example",
+			wantSANs:    []string{"dashboard.example.com", "192.0.2.1", "ops@example.com", "spiffe://example.com/dashboard"},
+			wantOK:      true,
+		},
+		{
+			name:  "unverified",
+			state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		},
+		{
+			name: "not TLS",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			subject, sans, ok := ClientIdentity(tt.state)
+			if subject != tt.wantSubject || !reflect.DeepEqual(sans, tt.wantSANs) || ok != tt.wantOK {
+				t.Errorf("ClientIdentity() = %v, %v, %v, want %v, %v, %v", subject, sans, ok, tt.wantSubject, tt.wantSANs, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+)
+
+// Pair ...
+// the paths of a certificate and its key
+type Pair struct {
+	CertPath string
+	KeyPath  string
+}
+
+// file names of the certificate and key in a Kubernetes TLS Secret
+const (
+	secretCertName = "tls.crt"
+	secretKeyName  = "tls.key"
+)
+
+// Store ...
+// serves certificates from files, choosing the certificate for each connection by its server name.
+// Certificates are given as pairs and found in a directory, where the first is served by default
+type Store struct {
+	pairs         []Pair
+	dir           string
+	rejectUnknown bool
+
+	mu        sync.RWMutex
+	providers []*Provider
+}
+
+// NewStore ...
+// returns a store of the certificates of the pairs and those in the directory, loading them.
+// Unknown server names are served the default certificate, unless rejectUnknown is set.
+// The store is returned along with the errors of the certificates which fail to load
+func NewStore(pairs []Pair, dir string, rejectUnknown bool) (*Store, error) {
+	s := &Store{pairs: pairs, dir: dir, rejectUnknown: rejectUnknown}
+	found, err := s.findPairs()
+	if err != nil {
+		return s, err
+	}
+	var errs []error
+	for _, pair := range found {
+		p, err := NewProvider(pair.CertPath, pair.KeyPath)
+		errs = append(errs, err)
+		s.providers = append(s.providers, p)
+	}
+	return s, errors.Join(errs...)
+}
+
+// findPairs returns the pairs, followed by the pairs in the directory
+func (s *Store) findPairs() ([]Pair, error) {
+	pairs := append([]Pair{}, s.pairs...)
+	if s.dir == "" {
+		return pairs, nil
+	}
+	found, err := FindPairs(s.dir)
+	if err != nil {
+		return pairs, err
+	}
+	return append(pairs, found...), nil
+}
+
+// FindPairs ...
+// returns the certificates in the directory, in order of name. Certificates are files named
+// NAME.crt with a key named NAME.key, or subdirectories holding a tls.crt and tls.key,
+// as mounted from a Kubernetes TLS Secret
+func FindPairs(dir string) (pairs []Pair, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the certificate directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		// skip the hidden files of Kubernetes volumes, such as ..data
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		entryPath := filepath.Join(dir, e.Name())
+		info, err := os.Stat(entryPath)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			pair := Pair{CertPath: filepath.Join(entryPath, secretCertName), KeyPath: filepath.Join(entryPath, secretKeyName)}
+			if fileExists(pair.CertPath) && fileExists(pair.KeyPath) {
+				pairs = append(pairs, pair)
+			}
+			continue
+		}
+		name, ok := strings.CutSuffix(e.Name(), ".crt")
+		if !ok {
+			continue
+		}
+		pair := Pair{CertPath: entryPath, KeyPath: filepath.Join(dir, name+".key")}
+		if fileExists(pair.KeyPath) {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// Reload ...
+// finds the certificates in the directory again and reloads every certificate,
+// keeping the current certificates of those which fail to load
+func (s *Store) Reload() error {
+	errs := []error{s.rescan()}
+	for _, p := range s.current() {
+		errs = append(errs, p.Reload())
+	}
+	return errors.Join(errs...)
+}
+
+// rescan adds the certificates which are new to the directory and removes those
+// which are no longer in it, loading the new certificates
+func (s *Store) rescan() error {
+	if s.dir == "" {
+		return nil
+	}
+	found, err := s.findPairs()
+	if err != nil {
+		return err
+	}
+	current := s.current()
+	existing := map[Pair]*Provider{}
+	var currentPairs []Pair
+	for _, p := range current {
+		pair := Pair{CertPath: p.certPath, KeyPath: p.keyPath}
+		existing[pair] = p
+		currentPairs = append(currentPairs, pair)
+	}
+	if reflect.DeepEqual(found, currentPairs) {
+		return nil
+	}
+	var errs []error
+	providers := make([]*Provider, 0, len(found))
+	for _, pair := range found {
+		if p, ok := existing[pair]; ok {
+			providers = append(providers, p)
+			continue
+		}
+		p, err := NewProvider(pair.CertPath, pair.KeyPath)
+		errs = append(errs, err)
+		providers = append(providers, p)
+	}
+	s.mu.Lock()
+	s.providers = providers
+	s.mu.Unlock()
+	// certificates no longer served are no longer expiring
+	served := map[string]bool{}
+	for _, pair := range found {
+		served[pair.CertPath] = true
+	}
+	for _, pair := range currentPairs {
+		if !served[pair.CertPath] {
+			metrics.DeleteCertificateExpiry(pair.CertPath)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// current returns the providers of the certificates
+func (s *Store) current() []*Provider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.providers
+}
+
+// Watch ...
+// reloads certificates when their files change, and finds certificates added to or removed
+// from the directory, checking every interval until the context is cancelled. Files are compared
+// by content, so that the symlinks swapped when a Kubernetes Secret is updated are followed
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.rescan(); err != nil {
+			slog.Error("failed to load TLS certificates from the directory", "path", s.dir, "error", err)
+		}
+		for _, p := range s.current() {
+			p.reloadIfChanged()
+		}
+	}
+}
+
+// GetCertificate ...
+// returns the certificate for the server name of the connection, for tls.Config.GetCertificate.
+// Exact names are preferred over wildcards. Without a match, the default certificate is returned,
+// or an error when unknown server names are rejected
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	serverName := ""
+	if hello != nil {
+		serverName = strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	}
+	var loaded []*tls.Certificate
+	for _, p := range s.current() {
+		if cert := p.Certificate(); cert != nil {
+			loaded = append(loaded, cert)
+		}
+	}
+	if len(loaded) == 0 {
+		return nil, s.Err()
+	}
+	if serverName != "" {
+		for _, cert := range loaded {
+			for _, name := range cert.Leaf.DNSNames {
+				if strings.EqualFold(name, serverName) {
+					return cert, nil
+				}
+			}
+		}
+		for _, cert := range loaded {
+			if cert.Leaf.VerifyHostname(serverName) == nil {
+				return cert, nil
+			}
+		}
+	}
+	if s.rejectUnknown {
+		return nil, fmt.Errorf("no certificate for server name %q", serverName)
+	}
+	return loaded[0], nil
+}
+
+// Err ...
+// returns the errors loading the certificates, when no certificate has loaded
+func (s *Store) Err() error {
+	providers := s.current()
+	if len(providers) == 0 {
+		return errors.New("no TLS certificates found")
+	}
+	var errs []error
+	for _, p := range providers {
+		err := p.Err()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,218 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStore_GetCertificate(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Now().Add(time.Hour)
+	var pairs []Pair
+	for _, names := range [][]string{{"default.example.com"}, {"*.example.com"}, {"www.example.com"}} {
+		pair := Pair{CertPath: path.Join(dir, names[0]+".crt"), KeyPath: path.Join(dir, names[0]+".key")}
+		writeCertificate(t, pair.CertPath, pair.KeyPath, notAfter, names...)
+		pairs = append(pairs, pair)
+	}
+	tests := []struct {
+		name          string
+		serverName    string
+		rejectUnknown bool
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:       "exact",
+			serverName: "www.example.com",
+			want:       "www.example.com",
+		},
+		{
+			name:       "exact preferred over an earlier wildcard",
+			serverName: "WWW.example.com.",
+			want:       "www.example.com",
+		},
+		{
+			name:       "wildcard",
+			serverName: "app.example.com",
+			want:       "*.example.com",
+		},
+		{
+			name:       "unknown served the default",
+			serverName: "example.org",
+			want:       "default.example.com",
+		},
+		{
+			name:       "no server name served the default",
+			serverName: "",
+			want:       "default.example.com",
+		},
+		{
+			name:          "unknown rejected",
+			serverName:    "example.org",
+			rejectUnknown: true,
+			wantErr:       true,
+		},
+		{
+			name:          "known with unknown rejected",
+			serverName:    "app.example.com",
+			rejectUnknown: true,
+			want:          "*.example.com",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStore(pairs, "", tt.rejectUnknown)
+			if err != nil {
+				t.Fatalf("NewStore() error = %v", err)
+			}
+			cert, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Store.GetCertificate(%q) error = %v, wantErr %v", tt.serverName, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := cert.Leaf.Subject.CommonName; got != tt.want {
+				t.Errorf("Store.GetCertificate(%q) = %v, want %v", tt.serverName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPairs(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Now().Add(time.Hour)
+	writeCertificate(t, path.Join(dir, "b.crt"), path.Join(dir, "b.key"), notAfter)
+	writeCertificate(t, path.Join(dir, "a.crt"), path.Join(dir, "a.key"), notAfter)
+	// a certificate without a key is skipped
+	writeCertificate(t, path.Join(dir, "c.crt"), path.Join(dir, "c.key"), notAfter)
+	if err := os.Remove(path.Join(dir, "c.key")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path.Join(dir, "secret"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeCertificate(t, path.Join(dir, "secret", "tls.crt"), path.Join(dir, "secret", "tls.key"), notAfter)
+	if err := os.Mkdir(path.Join(dir, "..data"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeCertificate(t, path.Join(dir, "..data", "tls.crt"), path.Join(dir, "..data", "tls.key"), notAfter)
+
+	got, err := FindPairs(dir)
+	if err != nil {
+		t.Fatalf("FindPairs() error = %v", err)
+	}
+	want := []Pair{
+		{CertPath: path.Join(dir, "a.crt"), KeyPath: path.Join(dir, "a.key")},
+		{CertPath: path.Join(dir, "b.crt"), KeyPath: path.Join(dir, "b.key")},
+		{CertPath: path.Join(dir, "secret", "tls.crt"), KeyPath: path.Join(dir, "secret", "tls.key")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindPairs() = %v, want %v", got, want)
+	}
+}
+
+func TestStore_Err(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(nil, dir, false)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if s.Err() == nil {
+		t.Error("Store.Err() = nil, want an error without certificates")
+	}
+	writeCertificate(t, path.Join(dir, "a.crt"), path.Join(dir, "a.key"), time.Now().Add(time.Hour))
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Store.Reload() error = %v", err)
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Store.Err() = %v, want nil once a certificate is added", err)
+	}
+}
+
+func TestStore_Watch(t *testing.T) {
+	dir := t.TempDir()
+	pair := Pair{CertPath: path.Join(dir, "a.crt"), KeyPath: path.Join(dir, "a.key")}
+	writeCertificate(t, pair.CertPath, pair.KeyPath, time.Now().Add(time.Hour), "a.example.com")
+	s, err := NewStore(nil, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx, 10*time.Millisecond)
+
+	// rotated
+	want := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	writeCertificate(t, pair.CertPath, pair.KeyPath, want, "a.example.com")
+	// added to the directory
+	writeCertificate(t, path.Join(dir, "b.crt"), path.Join(dir, "b.key"), time.Now().Add(time.Hour), "b.example.com")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a, _ := s.GetCertificate(&tls.ClientHelloInfo{ServerName: "a.example.com"})
+		_, bErr := s.GetCertificate(&tls.ClientHelloInfo{ServerName: "b.example.com"})
+		if a != nil && a.Leaf.NotAfter.Equal(want) && bErr == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Store.GetCertificate() after the directory changed = %v, b error %v, want the rotated and added certificates", a, bErr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStore_Reload_removed(t *testing.T) {
+	dir := t.TempDir()
+	writeCertificate(t, path.Join(dir, "a.crt"), path.Join(dir, "a.key"), time.Now().Add(time.Hour), "a.example.com")
+	writeCertificate(t, path.Join(dir, "b.crt"), path.Join(dir, "b.key"), time.Now().Add(time.Hour), "b.example.com")
+	s, err := NewStore(nil, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"b.crt", "b.key"} {
+		if err := os.Remove(path.Join(dir, f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Store.Reload() error = %v", err)
+	}
+	if _, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: "b.example.com"}); err == nil {
+		t.Errorf("Store.GetCertificate() of a removed certificate error = nil, want error")
+	}
+	if got := expirySeries(t); !got[path.Join(dir, "a.crt")] || got[path.Join(dir, "b.crt")] {
+		t.Errorf("certificate expiry series = %v, want only %v", got, path.Join(dir, "a.crt"))
+	}
+}
+
+// expirySeries returns the paths of the certificate expiry series which are exported
+func expirySeries(t *testing.T) map[string]bool {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]bool{}
+	for _, f := range families {
+		if f.GetName() != "ghs_tls_certificate_expiry_timestamp_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "path" {
+					paths[l.GetValue()] = true
+				}
+			}
+		}
+	}
+	return paths
+}
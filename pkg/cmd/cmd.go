@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
+	ghs "gitlab.com/BobyMCbobs/go-http-server/pkg/httpserver"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/logging"
+)
+
+const usage = `Usage: %v [command] [flags] [dir]
+
+Commands:
+  serve [dir]  serve a folder (default command, also run with a folder alone)
+  validate     parse the config and map files, reporting errors
+  routes       print the effective routing table
+  version      print the version
+
+Flags may be given before or after the folder. Run '%v <command> -h' for the flags of a command
+`
+
+// commands are the names of the commands
+var commands = []string{"serve", "validate", "routes", "version", "help"}
+
+// parseCommand returns the command named by the first arg and the args left for it.
+// Without a command, the args are for serve, so that '<dir>' alone serves the folder
+func parseCommand(args []string) (command string, rest []string, err error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "serve", args, nil
+	}
+	if slices.Contains(commands, args[0]) {
+		return args[0], args[1:], nil
+	}
+	if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+		return "serve", args, nil
+	}
+	return "", nil, fmt.Errorf("unknown command or folder '%v'", args[0])
+}
+
+// Run runs the command in the args, writing output to out
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	command, args, err := parseCommand(args)
+	if err != nil {
+		fmt.Fprintf(out, usage, common.AppName, common.AppName)
+		return err
+	}
+	switch command {
+	case "serve":
+		err = serve(ctx, args, out)
+	case "validate":
+		err = validate(args, out)
+	case "routes":
+		err = routes(args, out)
+	case "version":
+		version(out)
+	case "help":
+		fmt.Fprintf(out, usage, common.AppName, common.AppName)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+// newWebServer parses the flags of a command, returning a WebServer configured from the
+// config file, env and flags
+func newWebServer(command string, args []string, out io.Writer) (*ghs.WebServer, error) {
+	fs := flag.NewFlagSet(common.AppName+" "+command, flag.ContinueOnError)
+	fs.SetOutput(out)
+	cfg, configPath := configFlags(fs)
+	folders, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	switch len(folders) {
+	case 0:
+	case 1:
+		cfg.ServeFolder = &folders[0]
+	default:
+		return nil, fmt.Errorf("expected at most one folder to serve, got %v", folders)
+	}
+	if cfg.RedirectRoutes != nil && cfg.RedirectRoutesEnabled == nil {
+		enabled := true
+		cfg.RedirectRoutesEnabled = &enabled
+	}
+	opts := []ghs.Option{ghs.FromEnv(), ghs.WithConfig(ghs.SourceFlag, cfg), ghs.WithReloadOnSignal(), ghs.WithUpgradeOnSignal()}
+	if *configPath != "" {
+		opts = append(opts, ghs.WithConfigFile(*configPath))
+	}
+	ws := ghs.New(opts...)
+	// an invalid level or format is returned as the config error, when starting or validating
+	_ = logging.SetDefault(os.Stderr, ws.LogFormat, ws.LogLevel)
+	return ws, nil
+}
+
+// parseInterspersed parses the flags, which may come before or after the positional
+// arguments, returning the positional arguments. Arguments after -- are all positional
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		if consumed := len(args) - fs.NArg(); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func serve(ctx context.Context, args []string, out io.Writer) error {
+	if common.AppBuildMode == "development" {
+		_ = godotenv.Load(".env")
+	}
+	ws, err := newWebServer("serve", args, out)
+	if err != nil {
+		return err
+	}
+	slog.Info(common.AppName, "version", common.AppBuildVersion, "hash", common.AppBuildHash, "mode", common.AppBuildMode, "date", common.AppBuildDate)
+	return ws.Start(ctx)
+}
+
+func validate(args []string, out io.Writer) error {
+	ws, err := newWebServer("validate", args, out)
+	if err != nil {
+		return err
+	}
+	errs := ws.Validate()
+	for _, err := range errs {
+		fmt.Fprintln(out, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("found %v errors", len(errs))
+	}
+	fmt.Fprintln(out, "ok")
+	return nil
+}
+
+func routes(args []string, out io.Writer) error {
+	ws, err := newWebServer("routes", args, out)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tMETHODS\tMODE\tTARGET")
+	for _, r := range ws.Routes() {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", r.Path, strings.Join(r.Methods, ","), r.Mode, r.Target)
+	}
+	return tw.Flush()
+}
+
+func version(out io.Writer) {
+	fmt.Fprintf(out, "%v %v\n", common.AppName, common.AppBuildVersion)
+	fmt.Fprintf(out, "hash: %v\n", common.AppBuildHash)
+	fmt.Fprintf(out, "date: %v\n", common.AppBuildDate)
+	fmt.Fprintf(out, "mode: %v\n", common.AppBuildMode)
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ghs "gitlab.com/BobyMCbobs/go-http-server/pkg/httpserver"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		files        map[string]string
+		wantErr      bool
+		wantContains []string
+	}{
+		{
+			name:         "version",
+			args:         []string{"version"},
+			wantContains: []string{"go-http-server 0.0.0", "mode: development"},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"unknown"},
+			wantErr: true,
+		},
+		{
+			name:         "help",
+			args:         []string{"serve", "-h"},
+			wantContains: []string{"-port", "-history-mode"},
+		},
+		{
+			name:    "invalid flag",
+			args:    []string{"routes", "--gzip=maybe"},
+			wantErr: true,
+		},
+		{
+			name:         "routes",
+			args:         []string{"routes", "--redirect", "/b=https://example.com", "--redirect", "/a=/c", "--history-mode", "{dir}"},
+			wantContains: []string{"/a    GET      redirect  /c", "/b    GET      redirect  https://example.com", "/*    *        history"},
+		},
+		{
+			name:         "validate ok",
+			args:         []string{"validate", "--config", "{dir}/config.yaml"},
+			files:        map[string]string{"config.yaml": "appPort: :8080\n"},
+			wantContains: []string{"ok"},
+		},
+		{
+			name: "validate errors",
+			args: []string{"validate", "--config", "{dir}/config.yaml", "--headers", "--header-map", "{dir}/headers.yaml", "{dir}"},
+			files: map[string]string{
+				"config.yaml":  "appPort: :8080\n\nappPrt: :8081\n",
+				"headers.yaml": "X-Abc:\n  - a\n X-Def: b\n",
+				".ghs.yaml":    "historyMode: maybe\n",
+			},
+			wantErr: true,
+			wantContains: []string{
+				`config.yaml:3: unknown field "appPrt"`,
+				"headers.yaml:2: did not find expected key",
+				`.ghs.yaml:1: field "historyMode": cannot use string as bool`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := []string{}
+			for _, a := range tt.args {
+				args = append(args, strings.ReplaceAll(a, "{dir}", dir))
+			}
+			out := &bytes.Buffer{}
+			if err := Run(context.Background(), args, out); (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v\n%v", err, tt.wantErr, out.String())
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Run() output = %v, want to contain %v", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name        string
+		args        []string
+		wantCommand string
+		wantArgs    []string
+		wantErr     bool
+	}{
+		{
+			name:        "no args",
+			wantCommand: "serve",
+		},
+		{
+			name:        "command",
+			args:        []string{"routes", dir},
+			wantCommand: "routes",
+			wantArgs:    []string{dir},
+		},
+		{
+			name:        "flags",
+			args:        []string{"--port", ":3000", dir},
+			wantCommand: "serve",
+			wantArgs:    []string{"--port", ":3000", dir},
+		},
+		{
+			name:        "folder",
+			args:        []string{dir},
+			wantCommand: "serve",
+			wantArgs:    []string{dir},
+		},
+		{
+			name:    "neither a command nor a folder",
+			args:    []string{path.Join(dir, "valdate")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			command, args, err := parseCommand(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if command != tt.wantCommand || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("parseCommand() = %v %v, want %v %v", command, args, tt.wantCommand, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestNewWebServer(t *testing.T) {
+	t.Setenv("APP_PORT", ":8123")
+	ws, err := newWebServer("serve", []string{
+		"--port", ":8124",
+		"--gzip=false",
+		"--allowed-origins", "https://a.example.com,https://b.example.com",
+		"--header", "X-Abc=a",
+		"--header", "X-Abc=b",
+		"--template", "A=B",
+		"--shutdown-grace-timeout", "10s",
+		"--tls-certificate", "a.crt,a.key",
+		"--tls-certificate", "b.crt,b.key",
+		"./site",
+	}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []any{ws.AppPort, ws.GzipEnabled, ws.HTTPAllowedOrigins, ws.HeaderMapEnabled, ws.HeaderMap, ws.TemplateMap, ws.ShutdownGraceTimeout, ws.TLSCertificates, ws.ServeFolder, ws.ReloadOnSignal, ws.UpgradeOnSignal}
+	want := []any{
+		":8124", false, []string{"https://a.example.com", "https://b.example.com"},
+		true, map[string][]string{"X-Abc": {"a", "b"}}, map[string]string{"A": "B"}, 10 * time.Second,
+		[]ghs.TLSCertificate{{CertPath: "a.crt", KeyPath: "a.key"}, {CertPath: "b.crt", KeyPath: "b.key"}}, "./site", true, true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newWebServer() = %+v, want %+v", got, want)
+	}
+	if _, err := newWebServer("serve", []string{"./a", "./b"}, &bytes.Buffer{}); err == nil {
+		t.Errorf("newWebServer() with two folders wants error")
+	}
+	if _, err := newWebServer("serve", []string{"./a", "--port", ":8125", "./b"}, &bytes.Buffer{}); err == nil {
+		t.Errorf("newWebServer() with two folders around a flag wants error")
+	}
+}
+
+func TestNewWebServer_flagsAfterFolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantPort   string
+		wantFolder string
+	}{
+		{
+			name:       "flags after the folder",
+			args:       []string{"./site", "--port", ":8126"},
+			wantPort:   ":8126",
+			wantFolder: "./site",
+		},
+		{
+			name:       "flags around the folder",
+			args:       []string{"--gzip=false", "./site", "--port", ":8126"},
+			wantPort:   ":8126",
+			wantFolder: "./site",
+		},
+		{
+			name:       "folder after --",
+			args:       []string{"--port", ":8126", "--", "-site"},
+			wantPort:   ":8126",
+			wantFolder: "-site",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ws, err := newWebServer("serve", tt.args, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("newWebServer(%v) error = %v", tt.args, err)
+			}
+			if ws.AppPort != tt.wantPort || ws.ServeFolder != tt.wantFolder {
+				t.Errorf("newWebServer(%v) port = %v, folder = %v, want %v and %v", tt.args, ws.AppPort, ws.ServeFolder, tt.wantPort, tt.wantFolder)
+			}
+		})
+	}
+}
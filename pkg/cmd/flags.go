@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ghs "gitlab.com/BobyMCbobs/go-http-server/pkg/httpserver"
+)
+
+// stringFlag sets a config string when the flag is given
+type stringFlag struct{ target **string }
+
+func (f stringFlag) String() string {
+	if f.target == nil || *f.target == nil {
+		return ""
+	}
+	return **f.target
+}
+
+func (f stringFlag) Set(s string) error {
+	*f.target = &s
+	return nil
+}
+
+// boolFlag sets a config bool when the flag is given
+type boolFlag struct{ target **bool }
+
+func (f boolFlag) String() string {
+	if f.target == nil || *f.target == nil {
+		return ""
+	}
+	return strconv.FormatBool(**f.target)
+}
+
+func (f boolFlag) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*f.target = &v
+	return nil
+}
+
+func (f boolFlag) IsBoolFlag() bool {
+	return true
+}
+
+// durationFlag sets a config duration when the flag is given
+type durationFlag struct{ target **ghs.Duration }
+
+func (f durationFlag) String() string {
+	if f.target == nil || *f.target == nil {
+		return ""
+	}
+	return time.Duration(**f.target).String()
+}
+
+func (f durationFlag) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d := ghs.Duration(v)
+	*f.target = &d
+	return nil
+}
+
+// intFlag sets a config int when the flag is given
+type intFlag struct{ target **int }
+
+func (f intFlag) String() string {
+	if f.target == nil || *f.target == nil {
+		return ""
+	}
+	return strconv.Itoa(**f.target)
+}
+
+func (f intFlag) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*f.target = &v
+	return nil
+}
+
+// listFlag sets a config list from comma separated values when the flag is given
+type listFlag struct{ target **[]string }
+
+func (f listFlag) String() string {
+	if f.target == nil || *f.target == nil {
+		return ""
+	}
+	return strings.Join(**f.target, ",")
+}
+
+func (f listFlag) Set(s string) error {
+	v := strings.Split(s, ",")
+	*f.target = &v
+	return nil
+}
+
+// mapFlag adds a key=value pair to a config map each time the flag is given
+type mapFlag struct{ target **map[string]string }
+
+func (f mapFlag) String() string {
+	return ""
+}
+
+func (f mapFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got '%v'", s)
+	}
+	if *f.target == nil {
+		*f.target = &map[string]string{}
+	}
+	(**f.target)[k] = v
+	return nil
+}
+
+// certificateFlag adds a cert,key pair to the config certificates each time the flag is given
+type certificateFlag struct{ target **[]ghs.TLSCertificate }
+
+func (f certificateFlag) String() string {
+	return ""
+}
+
+func (f certificateFlag) Set(s string) error {
+	certPath, keyPath, ok := strings.Cut(s, ",")
+	if !ok {
+		return fmt.Errorf("expected cert,key, got '%v'", s)
+	}
+	if *f.target == nil {
+		*f.target = &[]ghs.TLSCertificate{}
+	}
+	**f.target = append(**f.target, ghs.TLSCertificate{CertPath: certPath, KeyPath: keyPath})
+	return nil
+}
+
+// headerFlag adds a header value each time the flag is given, enabling the header map
+type headerFlag struct{ cfg *ghs.Config }
+
+func (f headerFlag) String() string {
+	return ""
+}
+
+func (f headerFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected Name=value, got '%v'", s)
+	}
+	if f.cfg.HeaderMap == nil {
+		f.cfg.HeaderMap = &map[string][]string{}
+	}
+	(*f.cfg.HeaderMap)[k] = append((*f.cfg.HeaderMap)[k], v)
+	enabled := true
+	f.cfg.HeaderMapEnabled = &enabled
+	return nil
+}
+
+// configFlags registers a flag for each setting, returning the config which only has the
+// fields of the flags given, and the path of the config file
+func configFlags(fs *flag.FlagSet) (*ghs.Config, *string) {
+	cfg := &ghs.Config{}
+	configPath := fs.String("config", "", "the path to a YAML or JSON config file")
+	fs.Var(stringFlag{&cfg.AppPort}, "port", "the address to serve HTTP on, a TCP address, unix:/path or systemd:name (default :8080)")
+	fs.Var(stringFlag{&cfg.LogLevel}, "log-level", "the level to log at, one of debug, info, warn or error (default info)")
+	fs.Var(stringFlag{&cfg.LogFormat}, "log-format", "the format to log in, either text or json (default text)")
+	fs.Var(stringFlag{&cfg.AccessLogFormat}, "access-log-format", "the format of access logs, one of json, combined or template (default json)")
+	fs.Var(stringFlag{&cfg.AccessLogTemplate}, "access-log-template", "the Go template of access logs, for the template format")
+	fs.Var(listFlag{&cfg.AccessLogRedactHeaders}, "access-log-redact-headers", "comma separated headers to redact from access logs, along with the default sensitive headers")
+	fs.Var(listFlag{&cfg.AccessLogAllowHeaders}, "access-log-allow-headers", "comma separated headers which are the only headers written to access logs")
+	fs.Var(listFlag{&cfg.AccessLogMaskQueryParams}, "access-log-mask-query-params", "comma separated query parameters to mask in access logs, where * masks all")
+	fs.Var(stringFlag{&cfg.ServeFolder}, "serve-folder", "the folder to serve, also given as the first argument")
+	fs.Var(stringFlag{&cfg.Error404FilePath}, "error-404-file", "the file to serve when a file is not found")
+	fs.Var(boolFlag{&cfg.GzipEnabled}, "gzip", "gzip responses")
+	fs.Var(listFlag{&cfg.HTTPAllowedOrigins}, "allowed-origins", "comma separated origins allowed by CORS, which may have a * wildcard (default *)")
+	fs.Var(listFlag{&cfg.CORSAllowedMethods}, "cors-allowed-methods", "comma separated methods allowed by CORS (default GET)")
+	fs.Var(listFlag{&cfg.CORSAllowedHeaders}, "cors-allowed-headers", "comma separated request headers allowed by CORS, where * allows all (default *)")
+	fs.Var(listFlag{&cfg.CORSExposedHeaders}, "cors-exposed-headers", "comma separated response headers exposed by CORS")
+	fs.Var(boolFlag{&cfg.CORSAllowCredentials}, "cors-allow-credentials", "allow credentials in CORS requests, which requires allowed origins")
+	fs.Var(durationFlag{&cfg.CORSMaxAge}, "cors-max-age", "the time preflight responses may be cached for")
+	fs.Var(boolFlag{&cfg.CORSAllowPrivateNetwork}, "cors-allow-private-network", "allow CORS requests to a private network")
+	fs.Var(boolFlag{&cfg.HTTPSPortEnabled}, "https", "serve HTTPS")
+	fs.Var(stringFlag{&cfg.HTTPSPort}, "https-port", "the address to serve HTTPS on (default :8443)")
+	fs.Var(boolFlag{&cfg.H2CEnabled}, "h2c", "serve HTTP/2 without TLS on the app port, with prior knowledge and by upgrading")
+	fs.Var(intFlag{&cfg.H2CMaxConcurrentStreams}, "h2c-max-concurrent-streams", "the maximum number of concurrent streams per h2c connection (default 250)")
+	fs.Var(intFlag{&cfg.H2CMaxStreamBuffer}, "h2c-max-stream-buffer", "the size of the flow control window of each h2c stream, in bytes (default 1048576)")
+	fs.Var(boolFlag{&cfg.HTTP3Enabled}, "http3", "serve HTTP/3 over QUIC, on the UDP port of the HTTPS port")
+	fs.Var(boolFlag{&cfg.HTTPSRedirect}, "https-redirect", "redirect plain HTTP requests to HTTPS")
+	fs.Var(stringFlag{&cfg.HTTPSRedirectOrigin}, "https-redirect-origin", "the HTTPS origin to redirect to, such as https://example.com (default the HTTPS port of the requested host)")
+	fs.Var(durationFlag{&cfg.HSTSMaxAge}, "hsts-max-age", "the max age of the Strict-Transport-Security header on HTTPS responses, unset when zero")
+	fs.Var(boolFlag{&cfg.HSTSIncludeSubDomains}, "hsts-include-subdomains", "include subdomains in the Strict-Transport-Security header")
+	fs.Var(boolFlag{&cfg.HSTSPreload}, "hsts-preload", "allow preloading the Strict-Transport-Security header by browsers")
+	fs.Var(stringFlag{&cfg.TLSCertPath}, "tls-cert", "the path to the TLS certificate")
+	fs.Var(stringFlag{&cfg.TLSKeyPath}, "tls-key", "the path to the TLS key")
+	fs.Var(certificateFlag{&cfg.TLSCertificates}, "tls-certificate", "a cert,key pair of paths to a TLS certificate chosen by server name, may be repeated")
+	fs.Var(stringFlag{&cfg.TLSCertDir}, "tls-cert-dir", "a directory of NAME.crt and NAME.key TLS certificates chosen by server name")
+	fs.Var(boolFlag{&cfg.TLSRejectUnknownSNI}, "tls-reject-unknown-sni", "reject TLS handshakes for server names without a certificate")
+	fs.Var(stringFlag{&cfg.TLSPreset}, "tls-preset", "the TLS policy preset, modern or intermediate (default the Go defaults)")
+	fs.Var(stringFlag{&cfg.TLSMinVersion}, "tls-min-version", "the minimum TLS version, one of 1.0, 1.1, 1.2 or 1.3")
+	fs.Var(stringFlag{&cfg.TLSMaxVersion}, "tls-max-version", "the maximum TLS version, one of 1.0, 1.1, 1.2 or 1.3")
+	fs.Var(listFlag{&cfg.TLSCipherSuites}, "tls-cipher-suites", "comma separated TLS 1.2 cipher suites, such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	fs.Var(listFlag{&cfg.TLSCurves}, "tls-curves", "comma separated curve preferences, of X25519, P-256, P-384 and P-521")
+	fs.Var(boolFlag{&cfg.TLSSessionTicketsDisabled}, "tls-disable-session-tickets", "disable TLS session tickets")
+	fs.Var(listFlag{&cfg.TLSALPN}, "tls-alpn", "comma separated application protocols to negotiate, of h2 and http/1.1")
+	fs.Var(stringFlag{&cfg.TLSClientAuth}, "tls-client-auth", "the client certificate authentication, one of none, request, require-any, verify-if-given or require-and-verify (default none)")
+	fs.Var(listFlag{&cfg.TLSClientCAPaths}, "tls-client-ca", "comma separated paths to CA bundles to verify client certificates with")
+	fs.Var(boolFlag{&cfg.ACMEEnabled}, "acme", "obtain certificates for serving HTTPS with ACME, such as from Let's Encrypt")
+	fs.Var(stringFlag{&cfg.ACMEDirectoryURL}, "acme-directory-url", "the directory URL of the ACME server (default Let's Encrypt)")
+	fs.Var(listFlag{&cfg.ACMEHostnames}, "acme-hostnames", "comma separated hostnames to obtain certificates for with ACME")
+	fs.Var(stringFlag{&cfg.ACMECacheDir}, "acme-cache-dir", "the directory to store the ACME account and certificates in (default ./acme-cache)")
+	fs.Var(stringFlag{&cfg.ACMEEmail}, "acme-email", "the contact email of the ACME account")
+	fs.Var(boolFlag{&cfg.HeaderMapEnabled}, "headers", "add headers to responses from the header map")
+	fs.Var(stringFlag{&cfg.HeaderMapPath}, "header-map", "the path to the header map")
+	fs.Var(headerFlag{cfg}, "header", "a Name=value header to add to responses, may be repeated")
+	fs.Var(boolFlag{&cfg.HealthPortEnabled}, "health", "serve health checks")
+	fs.Var(stringFlag{&cfg.HealthPort}, "health-port", "the address to serve health checks on (default :8081)")
+	fs.Var(boolFlag{&cfg.MetricsPortEnabled}, "metrics", "serve metrics")
+	fs.Var(stringFlag{&cfg.MetricsPort}, "metrics-port", "the address to serve metrics on (default :2112)")
+	fs.Var(stringFlag{&cfg.UnixSocketMode}, "unix-socket-mode", "the octal file mode of Unix sockets, such as 0660")
+	fs.Var(stringFlag{&cfg.UnixSocketUser}, "unix-socket-user", "the user, by name or id, to own Unix sockets")
+	fs.Var(stringFlag{&cfg.UnixSocketGroup}, "unix-socket-group", "the group, by name or id, to own Unix sockets")
+	fs.Var(boolFlag{&cfg.ProxyProtocolEnabled}, "proxy-protocol", "accept PROXY protocol headers on the HTTP and HTTPS ports")
+	fs.Var(listFlag{&cfg.ProxyProtocolTrustedCIDRs}, "proxy-protocol-trusted-cidrs", "comma separated CIDRs or IPs trusted to send PROXY protocol headers")
+	fs.Var(stringFlag{&cfg.RealIPHeader}, "real-ip-header", "the header to use for the client IP, such as X-Forwarded-For or Forwarded")
+	fs.Var(listFlag{&cfg.TrustedProxies}, "trusted-proxies", "comma separated CIDRs or IPs of proxies trusted to set the real IP header")
+	fs.Var(boolFlag{&cfg.RedirectRoutesEnabled}, "redirects", "redirect paths from the redirect routes")
+	fs.Var(stringFlag{&cfg.RedirectRoutesPath}, "redirect-routes", "the path to the redirect routes")
+	fs.Var(mapFlag{&cfg.RedirectRoutes}, "redirect", "a /path=url redirect, may be repeated")
+	fs.Var(durationFlag{&cfg.ReloadInterval}, "reload-interval", "the interval to check config files for changes to reload, or 0 to disable (default 10s)")
+	fs.Var(boolFlag{&cfg.TemplateMapEnabled}, "templates", "template index.html from the template map")
+	fs.Var(stringFlag{&cfg.TemplateMapPath}, "template-map", "the path to the template map")
+	fs.Var(mapFlag{&cfg.TemplateMap}, "template", "a KEY=value to template index.html with, may be repeated")
+	fs.Var(boolFlag{&cfg.VueJSHistoryMode}, "history-mode", "rewrite requests, except for assets, to index.html")
+	fs.Var(durationFlag{&cfg.ShutdownGraceTimeout}, "shutdown-grace-timeout", "the time given to in-flight requests when shutting down (default 5s)")
+	fs.Var(durationFlag{&cfg.ShutdownPreStopDelay}, "shutdown-pre-stop-delay", "the time to keep serving with failing readiness when shutting down")
+	fs.Var(durationFlag{&cfg.ReadTimeout}, "read-timeout", "the time to read a request, including the body, or 0 for none (default 15s)")
+	fs.Var(durationFlag{&cfg.ReadHeaderTimeout}, "read-header-timeout", "the time to read the headers of a request, or 0 for none (default 10s)")
+	fs.Var(durationFlag{&cfg.WriteTimeout}, "write-timeout", "the time to write a response, or 0 for none (default 15s)")
+	fs.Var(durationFlag{&cfg.IdleTimeout}, "idle-timeout", "the time to keep idle keep-alive connections open, or 0 for the read timeout (default 2m0s)")
+	fs.Var(intFlag{&cfg.MaxHeaderBytes}, "max-header-bytes", "the maximum size of request headers (default 1048576)")
+	fs.Var(intFlag{&cfg.MaxConnections}, "max-connections", "the maximum number of concurrent HTTP and HTTPS connections, or 0 for no limit")
+	fs.Var(intFlag{&cfg.MaxConnectionsPerIP}, "max-connections-per-ip", "the maximum number of concurrent HTTP and HTTPS connections per client IP, or 0 for no limit")
+	return cfg, configPath
+}
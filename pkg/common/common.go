@@ -5,15 +5,22 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"sigs.k8s.io/yaml"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/logging"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/realip"
 )
 
 // AppBuild metadata
@@ -26,6 +33,30 @@ var (
 	AppServeFolderConfigName = ".ghs.yaml"
 )
 
+// defaults
+const (
+	DefaultAppPort              = ":8080"
+	DefaultHTTPSPort            = ":8443"
+	DefaultHealthPort           = ":8081"
+	DefaultMetricsPort          = ":2112"
+	DefaultTemplateMapPath      = "./template-map.yaml"
+	DefaultHeaderMapPath        = "./headers.yaml"
+	DefaultRedirectRoutesPath   = "./redirects.yaml"
+	Default404PageFileName      = "404.html"
+	DefaultShutdownGraceTimeout = 5 * time.Second
+	DefaultReloadInterval       = 10 * time.Second
+	DefaultLogLevel             = "info"
+	DefaultLogFormat            = "text"
+	DefaultAccessLogFormat      = "json"
+	DefaultReadTimeout          = 15 * time.Second
+	DefaultReadHeaderTimeout    = 10 * time.Second
+	DefaultWriteTimeout         = 15 * time.Second
+	DefaultIdleTimeout          = 120 * time.Second
+	DefaultMaxHeaderBytes       = http.DefaultMaxHeaderBytes
+	DefaultACMEDirectoryURL     = "https://acme-v02.api.letsencrypt.org/directory"
+	DefaultACMECacheDir         = "./acme-cache"
+)
+
 // GetAppHealthPortEnabled ...
 // enable the binding of a health port
 func GetAppHealthPortEnabled() (output bool) {
@@ -35,19 +66,19 @@ func GetAppHealthPortEnabled() (output bool) {
 // GetAppHealthPort ...
 // the port to bind the health service to
 func GetAppHealthPort() (output string) {
-	return GetEnvOrDefault("APP_HEALTH_PORT", ":8081")
+	return GetEnvOrDefault("APP_HEALTH_PORT", DefaultHealthPort)
 }
 
 // GetAppPort ...
 // the port to serve web traffic on
 func GetAppPort() (output string) {
-	return GetEnvOrDefault("APP_PORT", ":8080")
+	return GetEnvOrDefault("APP_PORT", DefaultAppPort)
 }
 
 // GetAppHTTPSPort ...
 // The port to serve HTTPS traffic on, if enabled.
 func GetAppHTTPSPort() (output string) {
-	return GetEnvOrDefault("APP_HTTPS_PORT", ":8443")
+	return GetEnvOrDefault("APP_HTTPS_PORT", DefaultHTTPSPort)
 }
 
 // GetAppHTTPSCrtPath ...
@@ -62,16 +93,166 @@ func GetAppHTTPSKeyPath() (output string) {
 	return GetEnvOrDefault("APP_HTTPS_KEY_PATH", "")
 }
 
+// GetAppHTTPSCrtDir ...
+// The directory of TLS certs and keys to choose from by server name for serving HTTPS
+func GetAppHTTPSCrtDir() (output string) {
+	return GetEnvOrDefault("APP_HTTPS_CRT_DIR", "")
+}
+
+// GetAppHTTPSRejectUnknownSNI ...
+// Whether to reject TLS handshakes for server names without a cert, rather than serving the default cert
+func GetAppHTTPSRejectUnknownSNI() (output bool) {
+	return GetEnvOrDefault("APP_HTTPS_REJECT_UNKNOWN_SNI", "false") == "true"
+}
+
+// GetAppHTTPSClientAuth ...
+// The client certificate authentication of HTTPS, one of none, request, require-any, verify-if-given or require-and-verify
+func GetAppHTTPSClientAuth() (output string) {
+	return GetEnvOrDefault("APP_HTTPS_CLIENT_AUTH", "none")
+}
+
+// GetAppHTTPSClientCAPaths ...
+// The CA bundles to verify client certificates with
+func GetAppHTTPSClientCAPaths() (output []string) {
+	return GetEnvListOrDefault("APP_HTTPS_CLIENT_CA_PATHS", nil)
+}
+
+// GetAppHTTPSTLSPreset ...
+// The TLS policy preset, modern or intermediate, otherwise the Go defaults
+func GetAppHTTPSTLSPreset() (output string) {
+	return GetEnvOrDefault("APP_HTTPS_TLS_PRESET", "")
+}
+
+// GetAppHTTPSMinVersion ...
+// The minimum TLS version, such as 1.2
+func GetAppHTTPSMinVersion() (output string) {
+	return GetEnvOrDefault("APP_HTTPS_MIN_VERSION", "")
+}
+
+// GetAppHTTPSMaxVersion ...
+// The maximum TLS version, such as 1.3
+func GetAppHTTPSMaxVersion() (output string) {
+	return GetEnvOrDefault("APP_HTTPS_MAX_VERSION", "")
+}
+
+// GetAppHTTPSCipherSuites ...
+// The TLS 1.2 cipher suites, by name
+func GetAppHTTPSCipherSuites() (output []string) {
+	return GetEnvListOrDefault("APP_HTTPS_CIPHER_SUITES", nil)
+}
+
+// GetAppHTTPSCurves ...
+// The curve preferences of TLS key exchanges, by name
+func GetAppHTTPSCurves() (output []string) {
+	return GetEnvListOrDefault("APP_HTTPS_CURVES", nil)
+}
+
+// GetAppHTTPSDisableSessionTickets ...
+// Whether to disable TLS session tickets, so sessions aren't resumed
+func GetAppHTTPSDisableSessionTickets() (output bool) {
+	return GetEnvOrDefault("APP_HTTPS_DISABLE_SESSION_TICKETS", "false") == "true"
+}
+
+// GetAppHTTPSALPN ...
+// The application protocols to negotiate with ALPN, h2 or http/1.1
+func GetAppHTTPSALPN() (output []string) {
+	return GetEnvListOrDefault("APP_HTTPS_ALPN", nil)
+}
+
+// GetACMEEnabled ...
+// Whether to obtain certificates for serving HTTPS with ACME
+func GetACMEEnabled() (output bool) {
+	return GetEnvOrDefault("APP_ACME_ENABLED", "false") == "true"
+}
+
+// GetACMEDirectoryURL ...
+// The directory URL of the ACME server to obtain certificates from
+func GetACMEDirectoryURL() (output string) {
+	return GetEnvOrDefault("APP_ACME_DIRECTORY_URL", DefaultACMEDirectoryURL)
+}
+
+// GetACMEHostnames ...
+// The hostnames to obtain certificates for with ACME
+func GetACMEHostnames() (output []string) {
+	return GetEnvListOrDefault("APP_ACME_HOSTNAMES", nil)
+}
+
+// GetACMECacheDir ...
+// The directory to store the ACME account and certificates in
+func GetACMECacheDir() (output string) {
+	return GetEnvOrDefault("APP_ACME_CACHE_DIR", DefaultACMECacheDir)
+}
+
+// GetACMEEmail ...
+// The contact email of the ACME account
+func GetACMEEmail() (output string) {
+	return GetEnvOrDefault("APP_ACME_EMAIL", "")
+}
+
 // GetAppEnableHTTPS ...
 // Whether to enable serving HTTPS.
 func GetAppEnableHTTPS() (output bool) {
 	return GetEnvOrDefault("APP_ENABLE_HTTPS", "false") == "true"
 }
 
+// GetH2CEnabled ...
+// Whether to serve HTTP/2 without TLS, as h2c, on the app port.
+func GetH2CEnabled() (output bool) {
+	return GetEnvOrDefault("APP_H2C_ENABLED", "false") == "true"
+}
+
+// GetH2CMaxConcurrentStreams ...
+// the maximum number of concurrent streams per h2c connection, or 0 for the default of 250
+func GetH2CMaxConcurrentStreams() (output int) {
+	return GetEnvIntOrDefault("APP_H2C_MAX_CONCURRENT_STREAMS", 0)
+}
+
+// GetH2CMaxStreamBuffer ...
+// the size of the h2c flow control window of each stream, or 0 for the default of 1MB
+func GetH2CMaxStreamBuffer() (output int) {
+	return GetEnvIntOrDefault("APP_H2C_MAX_STREAM_BUFFER", 0)
+}
+
+// GetAppHTTP3Enabled ...
+// Whether to serve HTTP/3 over QUIC, on the UDP port of the HTTPS port.
+func GetAppHTTP3Enabled() (output bool) {
+	return GetEnvOrDefault("APP_HTTP3_ENABLED", "false") == "true"
+}
+
+// GetAppHTTPSRedirect ...
+// Whether to redirect plain HTTP requests to HTTPS.
+func GetAppHTTPSRedirect() (output bool) {
+	return GetEnvOrDefault("APP_HTTPS_REDIRECT", "false") == "true"
+}
+
+// GetAppHTTPSRedirectOrigin ...
+// The HTTPS origin to redirect to, in place of the HTTPS port of the requested host.
+func GetAppHTTPSRedirectOrigin() (output string) {
+	return GetEnvOrDefault("APP_HTTPS_REDIRECT_ORIGIN", "")
+}
+
+// GetHSTSMaxAge ...
+// returns the max age of the Strict-Transport-Security header, which isn't set when zero
+func GetHSTSMaxAge() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_HSTS_MAX_AGE", 0)
+}
+
+// GetHSTSIncludeSubDomains ...
+// returns if the Strict-Transport-Security header includes subdomains
+func GetHSTSIncludeSubDomains() (output bool) {
+	return GetEnvOrDefault("APP_HSTS_INCLUDE_SUBDOMAINS", "false") == "true"
+}
+
+// GetHSTSPreload ...
+// returns if the Strict-Transport-Security header allows preloading by browsers
+func GetHSTSPreload() (output bool) {
+	return GetEnvOrDefault("APP_HSTS_PRELOAD", "false") == "true"
+}
+
 // GetAppMetricsPort ...
 // return the port which the app should serve metrics on
 func GetAppMetricsPort() (output string) {
-	return GetEnvOrDefault("APP_PORT_METRICS", ":2112")
+	return GetEnvOrDefault("APP_PORT_METRICS", DefaultMetricsPort)
 }
 
 // GetAppMetricsEnabled ...
@@ -86,6 +267,126 @@ func GetAppRealIPHeader() (output string) {
 	return GetEnvOrDefault("APP_HTTP_REAL_IP_HEADER", "")
 }
 
+// GetReadTimeout ...
+// the time to read a request, including the body
+func GetReadTimeout() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_READ_TIMEOUT", DefaultReadTimeout)
+}
+
+// GetReadHeaderTimeout ...
+// the time to read the headers of a request
+func GetReadHeaderTimeout() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_READ_HEADER_TIMEOUT", DefaultReadHeaderTimeout)
+}
+
+// GetWriteTimeout ...
+// the time to write a response, from the end of reading the request headers
+func GetWriteTimeout() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_WRITE_TIMEOUT", DefaultWriteTimeout)
+}
+
+// GetIdleTimeout ...
+// the time to keep idle keep-alive connections open
+func GetIdleTimeout() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_IDLE_TIMEOUT", DefaultIdleTimeout)
+}
+
+// GetMaxHeaderBytes ...
+// the maximum size of request headers
+func GetMaxHeaderBytes() (output int) {
+	return GetEnvIntOrDefault("APP_MAX_HEADER_BYTES", DefaultMaxHeaderBytes)
+}
+
+// GetMaxConnections ...
+// the maximum number of concurrent connections, or 0 for no limit
+func GetMaxConnections() (output int) {
+	return GetEnvIntOrDefault("APP_MAX_CONNECTIONS", 0)
+}
+
+// GetMaxConnectionsPerIP ...
+// the maximum number of concurrent connections per client IP, or 0 for no limit
+func GetMaxConnectionsPerIP() (output int) {
+	return GetEnvIntOrDefault("APP_MAX_CONNECTIONS_PER_IP", 0)
+}
+
+// GetUnixSocketMode ...
+// the octal file mode of Unix sockets, such as 0660
+func GetUnixSocketMode() (output string) {
+	return GetEnvOrDefault("APP_UNIX_SOCKET_MODE", "")
+}
+
+// GetUnixSocketUser ...
+// the user, by name or id, to own Unix sockets
+func GetUnixSocketUser() (output string) {
+	return GetEnvOrDefault("APP_UNIX_SOCKET_USER", "")
+}
+
+// GetUnixSocketGroup ...
+// the group, by name or id, to own Unix sockets
+func GetUnixSocketGroup() (output string) {
+	return GetEnvOrDefault("APP_UNIX_SOCKET_GROUP", "")
+}
+
+// GetProxyProtocolEnabled ...
+// accept PROXY protocol headers on the HTTP and HTTPS ports
+func GetProxyProtocolEnabled() (output bool) {
+	return GetEnvOrDefault("APP_PROXY_PROTOCOL_ENABLED", "false") == "true"
+}
+
+// GetProxyProtocolTrustedCIDRs ...
+// the CIDRs or IPs of load balancers trusted to send PROXY protocol headers
+func GetProxyProtocolTrustedCIDRs() (output []string) {
+	return GetEnvListOrDefault("APP_PROXY_PROTOCOL_TRUSTED_CIDRS", nil)
+}
+
+// GetTrustedProxies ...
+// the CIDRs or IPs of proxies trusted to set the real IP header
+func GetTrustedProxies() (output []string) {
+	return GetEnvListOrDefault("APP_HTTP_TRUSTED_PROXIES", nil)
+}
+
+// GetLogLevel ...
+// the level to log at, one of debug, info, warn or error
+func GetLogLevel() (output string) {
+	return GetEnvOrDefault("APP_LOG_LEVEL", DefaultLogLevel)
+}
+
+// GetLogFormat ...
+// the format to log in, either text or json
+func GetLogFormat() (output string) {
+	return GetEnvOrDefault("APP_LOG_FORMAT", DefaultLogFormat)
+}
+
+// GetAccessLogFormat ...
+// the format of access logs, one of json, combined or template
+func GetAccessLogFormat() (output string) {
+	return GetEnvOrDefault("APP_ACCESS_LOG_FORMAT", DefaultAccessLogFormat)
+}
+
+// GetAccessLogTemplate ...
+// the Go template of access logs, for the template format
+func GetAccessLogTemplate() (output string) {
+	return GetEnvOrDefault("APP_ACCESS_LOG_TEMPLATE", "")
+}
+
+// GetAccessLogRedactHeaders ...
+// headers to redact from access logs, along with the default sensitive headers
+func GetAccessLogRedactHeaders() (output []string) {
+	return GetEnvListOrDefault("APP_ACCESS_LOG_REDACT_HEADERS", nil)
+}
+
+// GetAccessLogAllowHeaders ...
+// when set, the only headers to write to access logs
+func GetAccessLogAllowHeaders() (output []string) {
+	return GetEnvListOrDefault("APP_ACCESS_LOG_ALLOW_HEADERS", nil)
+}
+
+// GetAccessLogMaskQueryParams ...
+// query parameters to mask the values of in access logs, where * masks all
+func GetAccessLogMaskQueryParams() (output []string) {
+	return GetEnvListOrDefault("APP_ACCESS_LOG_MASK_QUERY_PARAMS", nil)
+}
+
 // GetServeFolder ...
 // return the path of the folder to serve
 func GetServeFolder() (output string) {
@@ -96,7 +397,7 @@ func GetServeFolder() (output string) {
 // GetTemplateMapPath ...
 // return the path of the template map
 func GetTemplateMapPath() (output string) {
-	return GetEnvOrDefault("APP_TEMPLATE_MAP_PATH", "./template-map.yaml")
+	return GetEnvOrDefault("APP_TEMPLATE_MAP_PATH", DefaultTemplateMapPath)
 }
 
 // GetVuejsHistoryMode ...
@@ -120,13 +421,13 @@ func GetHeaderSetEnable() (output bool) {
 // GetHeaderMapPath ...
 // return the path of the header map
 func GetHeaderMapPath() (output string) {
-	return GetEnvOrDefault("APP_HEADER_MAP_PATH", "./headers.yaml")
+	return GetEnvOrDefault("APP_HEADER_MAP_PATH", DefaultHeaderMapPath)
 }
 
 // Get404PageFileName ...
 // return the name of the file to serve for 404 for standard directory serving
 func Get404PageFileName() (output string) {
-	return GetEnvOrDefault("APP_404_PAGE_FILE_NAME", "404.html")
+	return GetEnvOrDefault("APP_404_PAGE_FILE_NAME", Default404PageFileName)
 }
 
 // GetRedirectRoutesEnabled ...
@@ -138,7 +439,7 @@ func GetRedirectRoutesEnabled() (output bool) {
 // GetRedirectRoutesPath ...
 // return if redirecting routes should be enabled
 func GetRedirectRoutesPath() (output string) {
-	return GetEnvOrDefault("APP_REDIRECT_ROUTES_PATH", "./redirects.yaml")
+	return GetEnvOrDefault("APP_REDIRECT_ROUTES_PATH", DefaultRedirectRoutesPath)
 }
 
 // GetHTTPAllowedOrigins ...
@@ -150,7 +451,7 @@ func GetHTTPAllowedOrigins() (origins []string, err error) {
 		}
 		u, err := url.Parse(o)
 		if err != nil {
-			log.Printf("error: failed to parse URL '%v' from allowed origins; %v\n", o, err)
+			slog.Error("failed to parse URL from allowed origins", "origin", o, "error", err)
 			return origins, err
 		}
 		origins = append(origins, u.String())
@@ -158,6 +459,111 @@ func GetHTTPAllowedOrigins() (origins []string, err error) {
 	return origins, nil
 }
 
+// GetCORSAllowedMethods ...
+// returns the methods allowed by CORS
+func GetCORSAllowedMethods() (output []string) {
+	return GetEnvListOrDefault("APP_CORS_ALLOWED_METHODS", []string{http.MethodGet})
+}
+
+// GetCORSAllowedHeaders ...
+// returns the request headers allowed by CORS, where * allows all
+func GetCORSAllowedHeaders() (output []string) {
+	return GetEnvListOrDefault("APP_CORS_ALLOWED_HEADERS", []string{"*"})
+}
+
+// GetCORSExposedHeaders ...
+// returns the response headers exposed to browsers by CORS
+func GetCORSExposedHeaders() (output []string) {
+	return GetEnvListOrDefault("APP_CORS_EXPOSED_HEADERS", nil)
+}
+
+// GetCORSAllowCredentials ...
+// returns if CORS requests may include credentials
+func GetCORSAllowCredentials() (output bool) {
+	return GetEnvOrDefault("APP_CORS_ALLOW_CREDENTIALS", "false") == "true"
+}
+
+// GetCORSMaxAge ...
+// returns the time preflight responses may be cached for
+func GetCORSMaxAge() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_CORS_MAX_AGE", 0)
+}
+
+// GetCORSAllowPrivateNetwork ...
+// returns if CORS requests to a private network are allowed
+func GetCORSAllowPrivateNetwork() (output bool) {
+	return GetEnvOrDefault("APP_CORS_ALLOW_PRIVATE_NETWORK", "false") == "true"
+}
+
+// GetConfigPath ...
+// return the path of the config file
+func GetConfigPath() (output string) {
+	return GetEnvOrDefault("APP_CONFIG_PATH", "")
+}
+
+// GetShutdownPreStopDelay ...
+// the time to keep serving with failing readiness before shutting down
+func GetShutdownPreStopDelay() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_SHUTDOWN_PRE_STOP_DELAY", 0)
+}
+
+// GetShutdownGraceTimeout ...
+// the time given to servers to finish in-flight requests when shutting down
+func GetShutdownGraceTimeout() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_SHUTDOWN_GRACE_TIMEOUT", DefaultShutdownGraceTimeout)
+}
+
+// GetReloadInterval ...
+// the interval to check config files for changes, disabled when 0
+func GetReloadInterval() (output time.Duration) {
+	return GetEnvDurationOrDefault("APP_RELOAD_INTERVAL", DefaultReloadInterval)
+}
+
+// GetEnvListOrDefault ...
+// given an env var return it's comma separated values, else return a default
+func GetEnvListOrDefault(envName string, defaultValue []string) (output []string) {
+	value := os.Getenv(envName)
+	if value == "" {
+		return defaultValue
+	}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			output = append(output, v)
+		}
+	}
+	return output
+}
+
+// GetEnvDurationOrDefault ...
+// given an env var return it's value parsed as a duration, else return a default
+func GetEnvDurationOrDefault(envName string, defaultValue time.Duration) (output time.Duration) {
+	value := os.Getenv(envName)
+	if value == "" {
+		return defaultValue
+	}
+	output, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("failed to parse duration, using the default", "env", envName, "value", value, "default", defaultValue, "error", err)
+		return defaultValue
+	}
+	return output
+}
+
+// GetEnvIntOrDefault ...
+// given an env var return it's integer value, else return a default
+func GetEnvIntOrDefault(envName string, defaultValue int) (output int) {
+	value := os.Getenv(envName)
+	if value == "" {
+		return defaultValue
+	}
+	output, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("failed to parse integer, using the default", "env", envName, "value", value, "default", defaultValue, "error", err)
+		return defaultValue
+	}
+	return output
+}
+
 // GetEnvOrDefault ...
 // given an env var return it's value, else return a default
 func GetEnvOrDefault(envName string, defaultValue string) (output string) {
@@ -240,9 +646,13 @@ func WriteHeadersToResponse(w http.ResponseWriter, headerMap map[string][]string
 }
 
 // GetRequestIP ...
-// returns r.RemoteAddr unless RealIPHeader is set
+// returns the client IP resolved by the WebServer, otherwise r.RemoteAddr unless RealIPHeader is set
+//
+// Deprecated: use realip.FromRequest, which only trusts headers from trusted proxies
 func GetRequestIP(r *http.Request) (requestIP string) {
-	// TODO use from httpserver.WebServer
+	if clientIP, ok := realip.FromContext(r.Context()); ok {
+		return clientIP
+	}
 	realIPHeader := GetAppRealIPHeader()
 	headerValue := r.Header.Get(realIPHeader)
 	if realIPHeader == "" || headerValue == "" {
@@ -263,6 +673,8 @@ func (r *statusRecorder) WriteHeader(status int) {
 
 // Logging ...
 // a basic middleware for logging
+//
+// Deprecated: use logging.AccessLogger, which writes structured access logs
 func Logging(next http.Handler) http.Handler {
 	// log all requests
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -271,7 +683,10 @@ func Logging(next http.Handler) http.Handler {
 			ResponseWriter: w,
 		}
 		next.ServeHTTP(recorder, r)
-		log.Printf("%v %v %v %v %v %v %#v", recorder.Status, r.Method, r.URL, r.Proto, requestIP, r.RemoteAddr, r.Header)
+		redactor := logging.NewRedactor(nil, nil, nil)
+		u := *r.URL
+		u.RawQuery = redactor.Query(u.RawQuery)
+		log.Printf("%v %v %v %v %v %v %#v", recorder.Status, r.Method, u.String(), r.Proto, requestIP, r.RemoteAddr, redactor.Header(r.Header))
 	})
 }
 
@@ -283,6 +698,31 @@ type DotfileConfig struct {
 	HistoryMode      bool                `json:"historyMode"`
 	RedirectRoutes   map[string]string   `json:"redirectRoutes"`
 	TemplateMap      map[string]string   `json:"templateMap"`
+
+	historyModeSet bool
+}
+
+// UnmarshalJSON ...
+// unmarshals the dotfile, recording whether historyMode is set
+func (c *DotfileConfig) UnmarshalJSON(data []byte) error {
+	type dotfileConfig DotfileConfig
+	if err := json.Unmarshal(data, (*dotfileConfig)(c)); err != nil {
+		return err
+	}
+	var set struct {
+		HistoryMode *bool `json:"historyMode"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+	c.historyModeSet = set.HistoryMode != nil
+	return nil
+}
+
+// HistoryModeSet ...
+// returns whether historyMode is set in the dotfile, rather than left unset
+func (c *DotfileConfig) HistoryModeSet() bool {
+	return c.historyModeSet
 }
 
 // LoadDotfileConfig ...
@@ -11,6 +11,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/realip"
 )
 
 type responseWriter struct {
@@ -235,17 +238,1960 @@ func TestGetAppHTTPSKeyPath(t *testing.T) {
 	tests := []struct {
 		name       string
 		env        map[string]string
-		wantOutput string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_KEY_PATH": "/tmp/a.key"},
+			wantOutput: "/tmp/a.key",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSKeyPath(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSKeyPath() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppEnableHTTPS(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_ENABLE_HTTPS": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppEnableHTTPS(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppEnableHTTPS() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppMetricsPort(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: ":2112",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_PORT_METRICS": ":7373"},
+			wantOutput: ":7373",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppMetricsPort(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppMetricsPort() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppMetricsEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: true,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_METRICS_ENABLED": "false"},
+			wantOutput: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppMetricsEnabled(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppMetricsEnabled() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppRealIPHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTP_REAL_IP_HEADER": "X-Real-Ip"},
+			wantOutput: "X-Real-Ip",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppRealIPHeader(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppRealIPHeader() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetLogLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "info",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_LOG_LEVEL": "debug"},
+			wantOutput: "debug",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetLogLevel(); gotOutput != tt.wantOutput {
+				t.Errorf("GetLogLevel() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetLogFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "text",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_LOG_FORMAT": "json"},
+			wantOutput: "json",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetLogFormat(); gotOutput != tt.wantOutput {
+				t.Errorf("GetLogFormat() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetProxyProtocolEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_PROXY_PROTOCOL_ENABLED": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetProxyProtocolEnabled(); gotOutput != tt.wantOutput {
+				t.Errorf("GetProxyProtocolEnabled() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetProxyProtocolTrustedCIDRs(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_PROXY_PROTOCOL_TRUSTED_CIDRS": "10.0.0.0/8,192.0.2.1"},
+			wantOutput: []string{"10.0.0.0/8", "192.0.2.1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetProxyProtocolTrustedCIDRs(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetProxyProtocolTrustedCIDRs() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetUnixSocketMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_UNIX_SOCKET_MODE": "0660"},
+			wantOutput: "0660",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetUnixSocketMode(); gotOutput != tt.wantOutput {
+				t.Errorf("GetUnixSocketMode() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetUnixSocketUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_UNIX_SOCKET_USER": "www-data"},
+			wantOutput: "www-data",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetUnixSocketUser(); gotOutput != tt.wantOutput {
+				t.Errorf("GetUnixSocketUser() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetUnixSocketGroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_UNIX_SOCKET_GROUP": "www-data"},
+			wantOutput: "www-data",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetUnixSocketGroup(); gotOutput != tt.wantOutput {
+				t.Errorf("GetUnixSocketGroup() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetReadTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultReadTimeout,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_READ_TIMEOUT": "1m"},
+			wantOutput: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetReadTimeout(); gotOutput != tt.wantOutput {
+				t.Errorf("GetReadTimeout() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetReadHeaderTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultReadHeaderTimeout,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_READ_HEADER_TIMEOUT": "5s"},
+			wantOutput: 5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetReadHeaderTimeout(); gotOutput != tt.wantOutput {
+				t.Errorf("GetReadHeaderTimeout() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetWriteTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultWriteTimeout,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_WRITE_TIMEOUT": "0s"},
+			wantOutput: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetWriteTimeout(); gotOutput != tt.wantOutput {
+				t.Errorf("GetWriteTimeout() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetIdleTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultIdleTimeout,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_IDLE_TIMEOUT": "30s"},
+			wantOutput: 30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetIdleTimeout(); gotOutput != tt.wantOutput {
+				t.Errorf("GetIdleTimeout() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetMaxHeaderBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput int
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultMaxHeaderBytes,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_MAX_HEADER_BYTES": "8192"},
+			wantOutput: 8192,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetMaxHeaderBytes(); gotOutput != tt.wantOutput {
+				t.Errorf("GetMaxHeaderBytes() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetMaxConnections(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput int
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_MAX_CONNECTIONS": "1000"},
+			wantOutput: 1000,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetMaxConnections(); gotOutput != tt.wantOutput {
+				t.Errorf("GetMaxConnections() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetMaxConnectionsPerIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput int
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_MAX_CONNECTIONS_PER_IP": "10"},
+			wantOutput: 10,
+		},
+		{
+			name:       "invalid",
+			env:        map[string]string{"APP_MAX_CONNECTIONS_PER_IP": "ten"},
+			wantOutput: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetMaxConnectionsPerIP(); gotOutput != tt.wantOutput {
+				t.Errorf("GetMaxConnectionsPerIP() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetCORSAllowedMethods(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: []string{http.MethodGet},
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CORS_ALLOWED_METHODS": "GET, POST"},
+			wantOutput: []string{"GET", "POST"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetCORSAllowedMethods(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetCORSAllowedMethods() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetCORSAllowedHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: []string{"*"},
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CORS_ALLOWED_HEADERS": "Content-Type,Authorization"},
+			wantOutput: []string{"Content-Type", "Authorization"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetCORSAllowedHeaders(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetCORSAllowedHeaders() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetCORSExposedHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CORS_EXPOSED_HEADERS": "ETag"},
+			wantOutput: []string{"ETag"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetCORSExposedHeaders(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetCORSExposedHeaders() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetCORSAllowCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CORS_ALLOW_CREDENTIALS": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetCORSAllowCredentials(); gotOutput != tt.wantOutput {
+				t.Errorf("GetCORSAllowCredentials() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetCORSMaxAge(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CORS_MAX_AGE": "10m"},
+			wantOutput: 10 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetCORSMaxAge(); gotOutput != tt.wantOutput {
+				t.Errorf("GetCORSMaxAge() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetCORSAllowPrivateNetwork(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CORS_ALLOW_PRIVATE_NETWORK": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetCORSAllowPrivateNetwork(); gotOutput != tt.wantOutput {
+				t.Errorf("GetCORSAllowPrivateNetwork() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSCrtDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_CRT_DIR": "/tls"},
+			wantOutput: "/tls",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSCrtDir(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSCrtDir() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSRejectUnknownSNI(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_REJECT_UNKNOWN_SNI": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSRejectUnknownSNI(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSRejectUnknownSNI() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetACMEEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_ACME_ENABLED": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetACMEEnabled(); gotOutput != tt.wantOutput {
+				t.Errorf("GetACMEEnabled() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetACMEDirectoryURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultACMEDirectoryURL,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_ACME_DIRECTORY_URL": "https://localhost:14000/dir"},
+			wantOutput: "https://localhost:14000/dir",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetACMEDirectoryURL(); gotOutput != tt.wantOutput {
+				t.Errorf("GetACMEDirectoryURL() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetACMEHostnames(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_ACME_HOSTNAMES": "example.com, www.example.com"},
+			wantOutput: []string{"example.com", "www.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetACMEHostnames(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetACMEHostnames() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetACMECacheDir(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: DefaultACMECacheDir,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_ACME_CACHE_DIR": "/var/lib/ghs/acme"},
+			wantOutput: "/var/lib/ghs/acme",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetACMECacheDir(); gotOutput != tt.wantOutput {
+				t.Errorf("GetACMECacheDir() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetACMEEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_ACME_EMAIL": "admin@example.com"},
+			wantOutput: "admin@example.com",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetACMEEmail(); gotOutput != tt.wantOutput {
+				t.Errorf("GetACMEEmail() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSClientAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "none",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_CLIENT_AUTH": "require-and-verify"},
+			wantOutput: "require-and-verify",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSClientAuth(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSClientAuth() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSClientCAPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_CLIENT_CA_PATHS": "ca.crt,other-ca.crt"},
+			wantOutput: []string{"ca.crt", "other-ca.crt"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSClientCAPaths(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAppHTTPSClientCAPaths() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSTLSPreset(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_TLS_PRESET": "modern"},
+			wantOutput: "modern",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSTLSPreset(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSTLSPreset() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSMinVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_MIN_VERSION": "1.2"},
+			wantOutput: "1.2",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSMinVersion(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSMinVersion() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSMaxVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_MAX_VERSION": "1.3"},
+			wantOutput: "1.3",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSMaxVersion(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSMaxVersion() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSCipherSuites(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_CIPHER_SUITES": "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+			wantOutput: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSCipherSuites(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAppHTTPSCipherSuites() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSCurves(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_CURVES": "X25519,P-256"},
+			wantOutput: []string{"X25519", "P-256"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSCurves(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAppHTTPSCurves() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSDisableSessionTickets(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_DISABLE_SESSION_TICKETS": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSDisableSessionTickets(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSDisableSessionTickets() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSALPN(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_ALPN": "h2,http/1.1"},
+			wantOutput: []string{"h2", "http/1.1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSALPN(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAppHTTPSALPN() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_REDIRECT": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSRedirect(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSRedirect() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTPSRedirectOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTPS_REDIRECT_ORIGIN": "https://example.com"},
+			wantOutput: "https://example.com",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTPSRedirectOrigin(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTPSRedirectOrigin() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetHSTSMaxAge(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HSTS_MAX_AGE": "8760h"},
+			wantOutput: 365 * 24 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetHSTSMaxAge(); gotOutput != tt.wantOutput {
+				t.Errorf("GetHSTSMaxAge() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetHSTSIncludeSubDomains(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HSTS_INCLUDE_SUBDOMAINS": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetHSTSIncludeSubDomains(); gotOutput != tt.wantOutput {
+				t.Errorf("GetHSTSIncludeSubDomains() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetHSTSPreload(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HSTS_PRELOAD": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetHSTSPreload(); gotOutput != tt.wantOutput {
+				t.Errorf("GetHSTSPreload() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAppHTTP3Enabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTP3_ENABLED": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetAppHTTP3Enabled(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAppHTTP3Enabled() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetH2CEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput bool
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: false,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_H2C_ENABLED": "true"},
+			wantOutput: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetH2CEnabled(); gotOutput != tt.wantOutput {
+				t.Errorf("GetH2CEnabled() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetH2CMaxConcurrentStreams(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput int
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_H2C_MAX_CONCURRENT_STREAMS": "100"},
+			wantOutput: 100,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetH2CMaxConcurrentStreams(); gotOutput != tt.wantOutput {
+				t.Errorf("GetH2CMaxConcurrentStreams() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetH2CMaxStreamBuffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput int
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_H2C_MAX_STREAM_BUFFER": "65536"},
+			wantOutput: 65536,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetH2CMaxStreamBuffer(); gotOutput != tt.wantOutput {
+				t.Errorf("GetH2CMaxStreamBuffer() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: nil,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_HTTP_TRUSTED_PROXIES": "10.0.0.0/8, 192.0.2.1"},
+			wantOutput: []string{"10.0.0.0/8", "192.0.2.1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetTrustedProxies(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetTrustedProxies() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetAccessLogRedactHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput []string
 	}{
 		{
 			name:       "basic",
 			env:        nil,
-			wantOutput: "",
+			wantOutput: nil,
 		},
 		{
 			name:       "set env",
-			env:        map[string]string{"APP_HTTPS_KEY_PATH": "/tmp/a.key"},
-			wantOutput: "/tmp/a.key",
+			env:        map[string]string{"APP_ACCESS_LOG_REDACT_HEADERS": "X-Session, X-Secret,"},
+			wantOutput: []string{"X-Session", "X-Secret"},
 		},
 	}
 	for _, tt := range tests {
@@ -262,28 +2208,28 @@ func TestGetAppHTTPSKeyPath(t *testing.T) {
 				}
 			}()
 
-			if gotOutput := GetAppHTTPSKeyPath(); gotOutput != tt.wantOutput {
-				t.Errorf("GetAppHTTPSKeyPath() = %v, want %v", gotOutput, tt.wantOutput)
+			if gotOutput := GetAccessLogRedactHeaders(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAccessLogRedactHeaders() = %v, want %v", gotOutput, tt.wantOutput)
 			}
 		})
 	}
 }
 
-func TestGetAppEnableHTTPS(t *testing.T) {
+func TestGetAccessLogAllowHeaders(t *testing.T) {
 	tests := []struct {
 		name       string
 		env        map[string]string
-		wantOutput bool
+		wantOutput []string
 	}{
 		{
 			name:       "basic",
 			env:        nil,
-			wantOutput: false,
+			wantOutput: nil,
 		},
 		{
 			name:       "set env",
-			env:        map[string]string{"APP_ENABLE_HTTPS": "true"},
-			wantOutput: true,
+			env:        map[string]string{"APP_ACCESS_LOG_ALLOW_HEADERS": "User-Agent, Accept,"},
+			wantOutput: []string{"User-Agent", "Accept"},
 		},
 	}
 	for _, tt := range tests {
@@ -300,28 +2246,28 @@ func TestGetAppEnableHTTPS(t *testing.T) {
 				}
 			}()
 
-			if gotOutput := GetAppEnableHTTPS(); gotOutput != tt.wantOutput {
-				t.Errorf("GetAppEnableHTTPS() = %v, want %v", gotOutput, tt.wantOutput)
+			if gotOutput := GetAccessLogAllowHeaders(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAccessLogAllowHeaders() = %v, want %v", gotOutput, tt.wantOutput)
 			}
 		})
 	}
 }
 
-func TestGetAppMetricsPort(t *testing.T) {
+func TestGetAccessLogMaskQueryParams(t *testing.T) {
 	tests := []struct {
 		name       string
 		env        map[string]string
-		wantOutput string
+		wantOutput []string
 	}{
 		{
 			name:       "basic",
 			env:        nil,
-			wantOutput: ":2112",
+			wantOutput: nil,
 		},
 		{
 			name:       "set env",
-			env:        map[string]string{"APP_PORT_METRICS": ":7373"},
-			wantOutput: ":7373",
+			env:        map[string]string{"APP_ACCESS_LOG_MASK_QUERY_PARAMS": "token, code,"},
+			wantOutput: []string{"token", "code"},
 		},
 	}
 	for _, tt := range tests {
@@ -338,28 +2284,28 @@ func TestGetAppMetricsPort(t *testing.T) {
 				}
 			}()
 
-			if gotOutput := GetAppMetricsPort(); gotOutput != tt.wantOutput {
-				t.Errorf("GetAppMetricsPort() = %v, want %v", gotOutput, tt.wantOutput)
+			if gotOutput := GetAccessLogMaskQueryParams(); !reflect.DeepEqual(gotOutput, tt.wantOutput) {
+				t.Errorf("GetAccessLogMaskQueryParams() = %v, want %v", gotOutput, tt.wantOutput)
 			}
 		})
 	}
 }
 
-func TestGetAppMetricsEnabled(t *testing.T) {
+func TestGetAccessLogFormat(t *testing.T) {
 	tests := []struct {
 		name       string
 		env        map[string]string
-		wantOutput bool
+		wantOutput string
 	}{
 		{
 			name:       "basic",
 			env:        nil,
-			wantOutput: true,
+			wantOutput: "json",
 		},
 		{
 			name:       "set env",
-			env:        map[string]string{"APP_METRICS_ENABLED": "false"},
-			wantOutput: false,
+			env:        map[string]string{"APP_ACCESS_LOG_FORMAT": "combined"},
+			wantOutput: "combined",
 		},
 	}
 	for _, tt := range tests {
@@ -376,14 +2322,14 @@ func TestGetAppMetricsEnabled(t *testing.T) {
 				}
 			}()
 
-			if gotOutput := GetAppMetricsEnabled(); gotOutput != tt.wantOutput {
-				t.Errorf("GetAppMetricsEnabled() = %v, want %v", gotOutput, tt.wantOutput)
+			if gotOutput := GetAccessLogFormat(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAccessLogFormat() = %v, want %v", gotOutput, tt.wantOutput)
 			}
 		})
 	}
 }
 
-func TestGetAppRealIPHeader(t *testing.T) {
+func TestGetAccessLogTemplate(t *testing.T) {
 	tests := []struct {
 		name       string
 		env        map[string]string
@@ -396,8 +2342,8 @@ func TestGetAppRealIPHeader(t *testing.T) {
 		},
 		{
 			name:       "set env",
-			env:        map[string]string{"APP_HTTP_REAL_IP_HEADER": "X-Real-Ip"},
-			wantOutput: "X-Real-Ip",
+			env:        map[string]string{"APP_ACCESS_LOG_TEMPLATE": "{{.Status}} {{.Path}}"},
+			wantOutput: "{{.Status}} {{.Path}}",
 		},
 	}
 	for _, tt := range tests {
@@ -414,8 +2360,8 @@ func TestGetAppRealIPHeader(t *testing.T) {
 				}
 			}()
 
-			if gotOutput := GetAppRealIPHeader(); gotOutput != tt.wantOutput {
-				t.Errorf("GetAppRealIPHeader() = %v, want %v", gotOutput, tt.wantOutput)
+			if gotOutput := GetAccessLogTemplate(); gotOutput != tt.wantOutput {
+				t.Errorf("GetAccessLogTemplate() = %v, want %v", gotOutput, tt.wantOutput)
 			}
 		})
 	}
@@ -830,6 +2776,284 @@ func TestGetHTTPAllowedOrigins(t *testing.T) {
 	}
 }
 
+func TestGetConfigPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput string
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: "",
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_CONFIG_PATH": "./ghs.yaml"},
+			wantOutput: "./ghs.yaml",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetConfigPath(); gotOutput != tt.wantOutput {
+				t.Errorf("GetConfigPath() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetShutdownPreStopDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 0,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_SHUTDOWN_PRE_STOP_DELAY": "10s"},
+			wantOutput: 10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetShutdownPreStopDelay(); gotOutput != tt.wantOutput {
+				t.Errorf("GetShutdownPreStopDelay() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetShutdownGraceTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 5 * time.Second,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_SHUTDOWN_GRACE_TIMEOUT": "1m"},
+			wantOutput: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetShutdownGraceTimeout(); gotOutput != tt.wantOutput {
+				t.Errorf("GetShutdownGraceTimeout() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetReloadInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantOutput time.Duration
+	}{
+		{
+			name:       "basic",
+			env:        nil,
+			wantOutput: 10 * time.Second,
+		},
+		{
+			name:       "set env",
+			env:        map[string]string{"APP_RELOAD_INTERVAL": "1m"},
+			wantOutput: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+
+			if gotOutput := GetReloadInterval(); gotOutput != tt.wantOutput {
+				t.Errorf("GetReloadInterval() = %v, want %v", gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetEnvDurationOrDefault(t *testing.T) {
+	type args struct {
+		envName      string
+		defaultValue time.Duration
+	}
+	tests := []struct {
+		name       string
+		env        map[string]string
+		args       args
+		wantOutput time.Duration
+	}{
+		{
+			name: "basic",
+			args: args{
+				envName:      "BBBBBBBBBBB",
+				defaultValue: time.Second,
+			},
+			wantOutput: time.Second,
+		},
+		{
+			name: "set env",
+			env: map[string]string{
+				"BBBBBBBBBBB": "2s",
+			},
+			args: args{
+				envName:      "BBBBBBBBBBB",
+				defaultValue: time.Second,
+			},
+			wantOutput: 2 * time.Second,
+		},
+		{
+			name: "bad duration",
+			env: map[string]string{
+				"BBBBBBBBBBB": "two seconds",
+			},
+			args: args{
+				envName:      "BBBBBBBBBBB",
+				defaultValue: time.Second,
+			},
+			wantOutput: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{
+				tt.args.envName: os.Getenv(tt.args.envName),
+			}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+			if gotOutput := GetEnvDurationOrDefault(tt.args.envName, tt.args.defaultValue); gotOutput != tt.wantOutput {
+				t.Errorf("GetEnvDurationOrDefault(%v, %v) = %v, want %v", tt.args.envName, tt.args.defaultValue, gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestGetEnvIntOrDefault(t *testing.T) {
+	type args struct {
+		envName      string
+		defaultValue int
+	}
+	tests := []struct {
+		name       string
+		env        map[string]string
+		args       args
+		wantOutput int
+	}{
+		{
+			name: "basic",
+			args: args{
+				envName:      "BBBBBBBBBBB",
+				defaultValue: 1,
+			},
+			wantOutput: 1,
+		},
+		{
+			name: "set env",
+			env: map[string]string{
+				"BBBBBBBBBBB": "2",
+			},
+			args: args{
+				envName:      "BBBBBBBBBBB",
+				defaultValue: 1,
+			},
+			wantOutput: 2,
+		},
+		{
+			name: "bad integer",
+			env: map[string]string{
+				"BBBBBBBBBBB": "two",
+			},
+			args: args{
+				envName:      "BBBBBBBBBBB",
+				defaultValue: 1,
+			},
+			wantOutput: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{
+				tt.args.envName: os.Getenv(tt.args.envName),
+			}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+			if gotOutput := GetEnvIntOrDefault(tt.args.envName, tt.args.defaultValue); gotOutput != tt.wantOutput {
+				t.Errorf("GetEnvIntOrDefault(%v, %v) = %v, want %v", tt.args.envName, tt.args.defaultValue, gotOutput, tt.wantOutput)
+			}
+		})
+	}
+}
+
 func TestGetEnvOrDefault(t *testing.T) {
 	type args struct {
 		envName      string
@@ -1217,6 +3441,17 @@ func TestGetRequestIP(t *testing.T) {
 			},
 			wantRequestIP: "123.456.789.12",
 		},
+		{
+			name: "resolved by the web server",
+			args: args{
+				r: func() *http.Request {
+					req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+					req.Header.Set(RealIPHeader, "123.456.789.12")
+					return req.WithContext(realip.NewContext(req.Context(), "192.0.2.1"))
+				}(),
+			},
+			wantRequestIP: "192.0.2.1",
+		},
 	}
 	defer func() {
 		os.Unsetenv(RealIPHeader)
@@ -1336,6 +3571,7 @@ templateMap:
 				TemplateMap: map[string]string{
 					"AAA": "BBB",
 				},
+				historyModeSet: true,
 			},
 		},
 		{
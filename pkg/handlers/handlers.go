@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -14,6 +14,7 @@ import (
 	"github.com/NYTimes/gziphandler"
 
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
 )
 
 var (
@@ -55,22 +56,25 @@ func (h *Handler) serveHandlerVuejsHistoryMode() http.Handler {
 
 		// static files
 		if strings.Contains(req.URL.Path, ".") && !isDisallowed {
+			metrics.SetServingMode(req, metrics.ServingModeStatic)
 			handler.ServeHTTP(w, req)
 			return
 		}
 
+		metrics.SetServingMode(req, metrics.ServingModeTemplate)
+
 		// frontend views
 		indexPath := path.Join(h.ServeFolder, "/index.html")
 		tmpl, err := template.ParseFiles(indexPath)
 		if err != nil {
-			log.Println("warning: unable to parse template html:", err)
+			slog.Warn("unable to parse template html", "error", err)
 			http.Error(w, "500 internal error", http.StatusInternalServerError)
 			return
 		}
 		var buf bytes.Buffer
 		defer buf.Reset()
 		if err := tmpl.ExecuteTemplate(&buf, tmpl.Name(), h.TemplateMap); err != nil {
-			log.Println("warning: unable to execute template html:", err)
+			slog.Warn("unable to execute template html", "error", err)
 			http.Error(w, "500 internal error", http.StatusInternalServerError)
 		}
 		fmt.Fprint(w, &buf)
@@ -94,10 +98,12 @@ func (h *Handler) serveHandlerStandard() http.Handler {
 			}
 		}
 		if _, err := os.Stat(path.Join(h.ServeFolder, req.URL.Path)); err != nil || isDisallowed {
+			metrics.SetServingMode(req, metrics.ServingMode404)
 			w.WriteHeader(http.StatusNotFound)
 			http.ServeFile(w, req, path.Join(h.ServeFolder, h.Error404FilePath))
 			return
 		}
+		metrics.SetServingMode(req, metrics.ServingModeStatic)
 		handler.ServeHTTP(w, req)
 	})
 }
@@ -122,14 +128,15 @@ func (h *Handler) ServeHandler() (handler http.Handler) {
 func (h *Handler) ServeStandardRedirect(from string, to string) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		// TODO revisit disallowing certain paths like '/' or ''
+		metrics.SetServingMode(req, metrics.ServingModeRedirect)
 		toURL, err := url.Parse(to)
 		if err != nil {
-			log.Printf("Unable to parse redirection destination URL '%v' for route '%v'\n", to, from)
+			slog.Error("unable to parse redirection destination URL", "from", from, "to", to, "error", err)
 			http.Error(w, "fatal: unable to redirect to destination URL", http.StatusInternalServerError)
 			return
 		}
 		toURL.RawQuery = req.URL.Query().Encode()
-		log.Printf("redirecting '%v' -> '%v'\n", from, to)
+		slog.Debug("redirecting", "from", from, "to", to)
 		http.Redirect(w, req, toURL.String(), http.StatusTemporaryRedirect)
 	})
 }
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/adminserver"
+)
+
+// Check ...
+// a named readiness check, returning an error when not ready
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// Health configures the health handler
+type Health struct {
+	Enabled         bool
+	Port            string
+	ReadinessChecks []Check
+	ShutdownTimeout time.Duration
+	// ConfigureServer sets the timeouts and limits of the server, in place of the defaults
+	ConfigureServer func(*http.Server)
+
+	server adminserver.Server
+}
+
+// Livez ...
+// HTTP handler for liveness, ok whilst the process is serving
+func (h *Health) Livez(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// Readyz ...
+// HTTP handler for readiness, ok only when every readiness check passes
+func (h *Health) Readyz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	body := ""
+	for _, c := range h.ReadinessChecks {
+		if err := c.Check(); err != nil {
+			status = http.StatusServiceUnavailable
+			body += fmt.Sprintf("[-] %v: %v\n", c.Name, err)
+			continue
+		}
+		body += fmt.Sprintf("[+] %v: ok\n", c.Name)
+	}
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+// paths of the health endpoints
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Handler ...
+// returns the router for the health endpoints
+func (h *Health) Handler() http.Handler {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc(LivenessPath, h.Livez).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc(ReadinessPath, h.Readyz).Methods(http.MethodGet, http.MethodHead)
+	return router
+}
+
+// adminConfig returns the config of the server of the health endpoint
+func (h *Health) adminConfig() adminserver.Config {
+	return adminserver.Config{
+		Name:            "health",
+		Port:            h.Port,
+		ShutdownTimeout: h.ShutdownTimeout,
+		ConfigureServer: h.ConfigureServer,
+		Handler:         h.Handler(),
+	}
+}
+
+// Serve ...
+// serves health on the listener until shut down
+func (h *Health) Serve(l net.Listener) error {
+	return h.server.Serve(h.adminConfig(), l)
+}
+
+// Shutdown ...
+// gracefully stops serving health
+func (h *Health) Shutdown(ctx context.Context) error {
+	return h.server.Shutdown(ctx)
+}
+
+// Start ...
+// serves health on the port until the context is cancelled
+func (h *Health) Start(ctx context.Context) error {
+	if !h.Enabled {
+		return nil
+	}
+	return h.server.Start(ctx, h.adminConfig())
+}
+
+// Handle ...
+// serves health on the port until interrupted, or until true is received on the channel
+//
+// Deprecated: use Start, which returns errors and leaves handling signals to the caller
+func (h *Health) Handle(ch ...<-chan bool) {
+	if !h.Enabled {
+		return
+	}
+	h.server.Handle(h.adminConfig(), ch...)
+}
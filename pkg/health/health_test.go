@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth_Start(t *testing.T) {
+	type fields struct {
+		Enabled bool
+		Port    string
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		portInUse bool
+		wantErr   bool
+	}{
+		{
+			name: "basic",
+			fields: fields{
+				Enabled: true,
+				Port:    fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+			},
+		},
+		{
+			name: "not enabled",
+			fields: fields{
+				Enabled: false,
+				Port:    fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+			},
+		},
+		{
+			name: "port in use",
+			fields: fields{
+				Enabled: true,
+				Port:    fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+			},
+			portInUse: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if tt.portInUse {
+				l, err := net.Listen("tcp", tt.fields.Port)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer l.Close()
+			}
+			h := &Health{
+				Enabled: tt.fields.Enabled,
+				Port:    tt.fields.Port,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+			if err := h.Start(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Health.Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealth_Handler(t *testing.T) {
+	tests := []struct {
+		name            string
+		readinessChecks []Check
+		path            string
+		wantCode        int
+	}{
+		{
+			name:     "liveness",
+			path:     "/healthz",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "readiness with no checks",
+			path:     "/readyz",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "readiness with passing checks",
+			readinessChecks: []Check{
+				{Name: "a", Check: func() error { return nil }},
+				{Name: "b", Check: func() error { return nil }},
+			},
+			path:     "/readyz",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "readiness with a failing check",
+			readinessChecks: []Check{
+				{Name: "a", Check: func() error { return nil }},
+				{Name: "b", Check: func() error { return fmt.Errorf("not ready") }},
+			},
+			path:     "/readyz",
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "liveness ignores readiness checks",
+			readinessChecks: []Check{
+				{Name: "a", Check: func() error { return fmt.Errorf("not ready") }},
+			},
+			path:     "/healthz",
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := &Health{
+				ReadinessChecks: tt.readinessChecks,
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			h.Handler().ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("Health.Handler() %v = %v, want %v (%v)", tt.path, rec.Code, tt.wantCode, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"slices"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/certs"
+)
+
+// newACMEManager returns the manager of certificates obtained with ACME, for the allowed hostnames
+func (w *WebServer) newACMEManager() *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(w.ACMECacheDir),
+		HostPolicy: autocert.HostWhitelist(w.ACMEHostnames...),
+		Client:     &acme.Client{DirectoryURL: w.ACMEDirectoryURL},
+		Email:      w.ACMEEmail,
+	}
+}
+
+// loadACME sets the TLS config to serve certificates obtained with ACME, answering TLS-ALPN-01
+// challenges. Server names which aren't allowed are served from the certificate files, when given
+func (w *WebServer) loadACME() error {
+	m := w.newACMEManager()
+	w.acmeManager = m
+	var store *certs.Store
+	var err error
+	if w.TLSCertPath != "" || len(w.TLSCertificates) > 0 || w.TLSCertDir != "" {
+		store, err = w.newCertStore()
+		w.certStore = store
+	}
+	w.TLSConfig = &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			challenge := slices.Contains(hello.SupportedProtos, acme.ALPNProto)
+			if store != nil && !challenge && m.HostPolicy(context.Background(), hello.ServerName) != nil {
+				return store.GetCertificate(hello)
+			}
+			return m.GetCertificate(hello)
+		},
+		NextProtos: []string{"h2", "http/1.1", acme.ALPNProto},
+	}
+	return err
+}
+
+// acmeHandler answers HTTP-01 challenges, serving every other request with next
+func (w *WebServer) acmeHandler(next http.Handler) http.Handler {
+	if w.acmeManager == nil {
+		return next
+	}
+	return w.acmeManager.HTTPHandler(next)
+}
+
+// validateACME ensures that ACME has hostnames to obtain certificates for, and serves HTTPS
+func (w *WebServer) validateACME() error {
+	if !w.ACMEEnabled {
+		return nil
+	}
+	var errs []error
+	if len(w.ACMEHostnames) == 0 {
+		errs = append(errs, errors.New("ACME requires the hostnames to obtain certificates for"))
+	}
+	if !w.HTTPSPortEnabled {
+		errs = append(errs, errors.New("ACME requires HTTPS to be enabled"))
+	}
+	if w.ACMECacheDir == "" {
+		errs = append(errs, errors.New("ACME requires a cache directory for certificates"))
+	}
+	return errors.Join(errs...)
+}
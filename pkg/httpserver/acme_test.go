@@ -0,0 +1,151 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/acme"
+)
+
+func TestWebServer_acmeHandler(t *testing.T) {
+	folder := t.TempDir()
+	if err := os.WriteFile(path.Join(folder, "index.html"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ws := New(
+		WithServeFolder(folder),
+		WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true)}),
+		WithACME("https://acme.invalid/directory", t.TempDir(), "", "example.com"),
+	)
+	ws.accessLogOut = io.Discard
+	ws.build()
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "served",
+			path:     "/",
+			wantCode: http.StatusOK,
+			wantBody: "hello",
+		},
+		{
+			name:     "unknown challenge",
+			path:     "/.well-known/acme-challenge/token",
+			wantCode: http.StatusNotFound,
+			wantBody: "acme/autocert",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = "example.com"
+			rec := httptest.NewRecorder()
+			ws.server.Handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %v code = %v, want %v", tt.path, rec.Code, tt.wantCode)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET %v body = %q, want it to contain %q", tt.path, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWebServer_LoadTLS_acme(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+	writeTestCertificate(t, certPath, keyPath, "static.example.com")
+	ws := New(
+		WithServeFolder(t.TempDir()),
+		WithHTTPS("127.0.0.1:0", certPath, keyPath),
+		WithACME("https://acme.invalid/directory", t.TempDir(), "", "example.com"),
+	)
+	if _, err := ws.LoadTLS(); err != nil {
+		t.Fatalf("WebServer.LoadTLS() error = %v", err)
+	}
+	if got := ws.TLSConfig.NextProtos; !strings.Contains(strings.Join(got, ","), acme.ALPNProto) {
+		t.Errorf("WebServer.LoadTLS() next protos = %v, want %v for TLS-ALPN-01 challenges", got, acme.ALPNProto)
+	}
+	tests := []struct {
+		name       string
+		hello      *tls.ClientHelloInfo
+		want       string
+		wantErr    bool
+		wantStatic bool
+	}{
+		{
+			name:       "name not allowed is served from the files",
+			hello:      &tls.ClientHelloInfo{ServerName: "static.example.com"},
+			wantStatic: true,
+		},
+		{
+			name:    "challenge for a name not allowed",
+			hello:   &tls.ClientHelloInfo{ServerName: "static.example.com", SupportedProtos: []string{acme.ALPNProto}},
+			wantErr: true,
+		},
+		{
+			name:    "challenge without a pending authorization",
+			hello:   &tls.ClientHelloInfo{ServerName: "example.com", SupportedProtos: []string{acme.ALPNProto}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := ws.TLSConfig.GetCertificate(tt.hello)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCertificate(%v) error = %v, wantErr %v", tt.hello.ServerName, err, tt.wantErr)
+			}
+			if tt.wantStatic && cert.Leaf.Subject.CommonName != "static.example.com" {
+				t.Errorf("GetCertificate(%v) = %v, want the static certificate", tt.hello.ServerName, cert.Leaf.Subject.CommonName)
+			}
+		})
+	}
+}
+
+func TestWebServer_LoadTLS_acmeNegotiatesH2(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+	writeTestCertificate(t, certPath, keyPath, "static.example.com")
+	ws := New(
+		WithServeFolder(t.TempDir()),
+		WithHTTPS("127.0.0.1:0", certPath, keyPath),
+		WithACME("https://acme.invalid/directory", t.TempDir(), "", "example.com"),
+	)
+	if _, err := ws.LoadTLS(); err != nil {
+		t.Fatalf("WebServer.LoadTLS() error = %v", err)
+	}
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	server := tls.Server(serverConn, ws.TLSConfig)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Handshake()
+	}()
+	client := tls.Client(clientConn, &tls.Config{
+		ServerName:         "static.example.com",
+		NextProtos:         []string{"h2", "http/1.1"},
+		InsecureSkipVerify: true,
+	})
+	if err := client.Handshake(); err != nil {
+		t.Fatalf("handshake error = %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("server handshake error = %v", err)
+	}
+	if got := client.ConnectionState().NegotiatedProtocol; got != "h2" {
+		t.Errorf("negotiated protocol = %q, want h2", got)
+	}
+}
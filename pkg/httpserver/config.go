@@ -0,0 +1,448 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/logging"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/realip"
+)
+
+// Source is where a configuration value came from.
+// Sources are listed in order of precedence, with later sources overriding earlier ones
+type Source int
+
+// configuration sources
+const (
+	SourceDefault Source = iota
+	SourceConfigFile
+	SourceEnv
+	SourceOption
+	SourceFlag
+	SourceDotfile
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceConfigFile:
+		return "config file"
+	case SourceEnv:
+		return "env"
+	case SourceOption:
+		return "option"
+	case SourceFlag:
+		return "flag"
+	case SourceDotfile:
+		return "dotfile"
+	}
+	return "unknown"
+}
+
+// Duration is a time.Duration which is read from a string such as 5s
+type Duration time.Duration
+
+// UnmarshalJSON reads a duration from a string with a unit. Bare numbers are rejected,
+// as their unit is ambiguous
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("invalid duration %v, expected a string with a unit such as \"15s\"", string(b))
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes a duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config configures a WebServer, as loaded from a YAML or JSON config file.
+// Each field matches the WebServer field of the same name and fields which aren't set are left alone
+type Config struct {
+	ACMECacheDir              *string                    `json:"acmeCacheDir,omitempty"`
+	ACMEDirectoryURL          *string                    `json:"acmeDirectoryURL,omitempty"`
+	ACMEEmail                 *string                    `json:"acmeEmail,omitempty"`
+	ACMEEnabled               *bool                      `json:"acmeEnabled,omitempty"`
+	ACMEHostnames             *[]string                  `json:"acmeHostnames,omitempty"`
+	AccessLogAllowHeaders     *[]string                  `json:"accessLogAllowHeaders,omitempty"`
+	AccessLogFormat           *string                    `json:"accessLogFormat,omitempty"`
+	AccessLogMaskQueryParams  *[]string                  `json:"accessLogMaskQueryParams,omitempty"`
+	AccessLogRedactHeaders    *[]string                  `json:"accessLogRedactHeaders,omitempty"`
+	AccessLogTemplate         *string                    `json:"accessLogTemplate,omitempty"`
+	AppPort                   *string                    `json:"appPort,omitempty"`
+	CORSAllowCredentials      *bool                      `json:"corsAllowCredentials,omitempty"`
+	CORSAllowPrivateNetwork   *bool                      `json:"corsAllowPrivateNetwork,omitempty"`
+	CORSAllowedHeaders        *[]string                  `json:"corsAllowedHeaders,omitempty"`
+	CORSAllowedMethods        *[]string                  `json:"corsAllowedMethods,omitempty"`
+	CORSExposedHeaders        *[]string                  `json:"corsExposedHeaders,omitempty"`
+	CORSMaxAge                *Duration                  `json:"corsMaxAge,omitempty"`
+	CORSRules                 *[]CORSRule                `json:"corsRules,omitempty"`
+	Error404FilePath          *string                    `json:"error404FilePath,omitempty"`
+	GzipEnabled               *bool                      `json:"gzipEnabled,omitempty"`
+	H2CEnabled                *bool                      `json:"h2cEnabled,omitempty"`
+	H2CMaxConcurrentStreams   *int                       `json:"h2cMaxConcurrentStreams,omitempty"`
+	H2CMaxStreamBuffer        *int                       `json:"h2cMaxStreamBuffer,omitempty"`
+	HSTSIncludeSubDomains     *bool                      `json:"hstsIncludeSubDomains,omitempty"`
+	HSTSMaxAge                *Duration                  `json:"hstsMaxAge,omitempty"`
+	HSTSPreload               *bool                      `json:"hstsPreload,omitempty"`
+	HTTP3Enabled              *bool                      `json:"http3Enabled,omitempty"`
+	HTTPAllowedOrigins        *[]string                  `json:"httpAllowedOrigins,omitempty"`
+	HTTPSPort                 *string                    `json:"httpsPort,omitempty"`
+	HTTPSPortEnabled          *bool                      `json:"httpsPortEnabled,omitempty"`
+	HTTPSRedirect             *bool                      `json:"httpsRedirect,omitempty"`
+	HTTPSRedirectOrigin       *string                    `json:"httpsRedirectOrigin,omitempty"`
+	HeaderMap                 *map[string][]string       `json:"headerMap,omitempty"`
+	HeaderMapEnabled          *bool                      `json:"headerMapEnabled,omitempty"`
+	HeaderMapPath             *string                    `json:"headerMapPath,omitempty"`
+	HealthPort                *string                    `json:"healthPort,omitempty"`
+	HealthPortEnabled         *bool                      `json:"healthPortEnabled,omitempty"`
+	IdleTimeout               *Duration                  `json:"idleTimeout,omitempty"`
+	ListenerTimeouts          *map[string]ServerTimeouts `json:"listenerTimeouts,omitempty"`
+	LogFormat                 *string                    `json:"logFormat,omitempty"`
+	LogLevel                  *string                    `json:"logLevel,omitempty"`
+	MaxConnections            *int                       `json:"maxConnections,omitempty"`
+	MaxConnectionsPerIP       *int                       `json:"maxConnectionsPerIP,omitempty"`
+	MaxHeaderBytes            *int                       `json:"maxHeaderBytes,omitempty"`
+	MetricsPort               *string                    `json:"metricsPort,omitempty"`
+	MetricsPortEnabled        *bool                      `json:"metricsPortEnabled,omitempty"`
+	ProxyProtocolEnabled      *bool                      `json:"proxyProtocolEnabled,omitempty"`
+	ProxyProtocolTrustedCIDRs *[]string                  `json:"proxyProtocolTrustedCIDRs,omitempty"`
+	ReadHeaderTimeout         *Duration                  `json:"readHeaderTimeout,omitempty"`
+	ReadTimeout               *Duration                  `json:"readTimeout,omitempty"`
+	RealIPHeader              *string                    `json:"realIPHeader,omitempty"`
+	RedirectRoutes            *map[string]string         `json:"redirectRoutes,omitempty"`
+	RedirectRoutesEnabled     *bool                      `json:"redirectRoutesEnabled,omitempty"`
+	RedirectRoutesPath        *string                    `json:"redirectRoutesPath,omitempty"`
+	ReloadInterval            *Duration                  `json:"reloadInterval,omitempty"`
+	ServeFolder               *string                    `json:"serveFolder,omitempty"`
+	ShutdownGraceTimeout      *Duration                  `json:"shutdownGraceTimeout,omitempty"`
+	ShutdownPreStopDelay      *Duration                  `json:"shutdownPreStopDelay,omitempty"`
+	TLSALPN                   *[]string                  `json:"tlsALPN,omitempty"`
+	TLSCertDir                *string                    `json:"tlsCertDir,omitempty"`
+	TLSCertPath               *string                    `json:"tlsCertPath,omitempty"`
+	TLSCertificates           *[]TLSCertificate          `json:"tlsCertificates,omitempty"`
+	TLSCipherSuites           *[]string                  `json:"tlsCipherSuites,omitempty"`
+	TLSClientAuth             *string                    `json:"tlsClientAuth,omitempty"`
+	TLSClientAuthRules        *[]TLSClientAuthRule       `json:"tlsClientAuthRules,omitempty"`
+	TLSClientCAPaths          *[]string                  `json:"tlsClientCAPaths,omitempty"`
+	TLSCurves                 *[]string                  `json:"tlsCurves,omitempty"`
+	TLSKeyPath                *string                    `json:"tlsKeyPath,omitempty"`
+	TLSMaxVersion             *string                    `json:"tlsMaxVersion,omitempty"`
+	TLSMinVersion             *string                    `json:"tlsMinVersion,omitempty"`
+	TLSPreset                 *string                    `json:"tlsPreset,omitempty"`
+	TLSRejectUnknownSNI       *bool                      `json:"tlsRejectUnknownSNI,omitempty"`
+	TLSSessionTicketsDisabled *bool                      `json:"tlsSessionTicketsDisabled,omitempty"`
+	TemplateMap               *map[string]string         `json:"templateMap,omitempty"`
+	TemplateMapEnabled        *bool                      `json:"templateMapEnabled,omitempty"`
+	TemplateMapPath           *string                    `json:"templateMapPath,omitempty"`
+	TrustedProxies            *[]string                  `json:"trustedProxies,omitempty"`
+	UnixSocketGroup           *string                    `json:"unixSocketGroup,omitempty"`
+	UnixSocketMode            *string                    `json:"unixSocketMode,omitempty"`
+	UnixSocketUser            *string                    `json:"unixSocketUser,omitempty"`
+	VueJSHistoryMode          *bool                      `json:"historyMode,omitempty"`
+	WriteTimeout              *Duration                  `json:"writeTimeout,omitempty"`
+}
+
+// configLayer is configuration from a source
+type configLayer struct {
+	source Source
+	config *Config
+}
+
+// envSettings sets configuration from each environment variable
+var envSettings = []struct {
+	env   []string
+	apply func(*Config)
+}{
+	{env: []string{"APP_ACME_CACHE_DIR"}, apply: func(c *Config) { c.ACMECacheDir = pointer(common.GetACMECacheDir()) }},
+	{env: []string{"APP_ACME_DIRECTORY_URL"}, apply: func(c *Config) { c.ACMEDirectoryURL = pointer(common.GetACMEDirectoryURL()) }},
+	{env: []string{"APP_ACME_EMAIL"}, apply: func(c *Config) { c.ACMEEmail = pointer(common.GetACMEEmail()) }},
+	{env: []string{"APP_ACME_ENABLED"}, apply: func(c *Config) { c.ACMEEnabled = pointer(common.GetACMEEnabled()) }},
+	{env: []string{"APP_ACME_HOSTNAMES"}, apply: func(c *Config) { c.ACMEHostnames = pointer(common.GetACMEHostnames()) }},
+	{env: []string{"APP_ACCESS_LOG_ALLOW_HEADERS"}, apply: func(c *Config) { c.AccessLogAllowHeaders = pointer(common.GetAccessLogAllowHeaders()) }},
+	{env: []string{"APP_ACCESS_LOG_MASK_QUERY_PARAMS"}, apply: func(c *Config) { c.AccessLogMaskQueryParams = pointer(common.GetAccessLogMaskQueryParams()) }},
+	{env: []string{"APP_ACCESS_LOG_REDACT_HEADERS"}, apply: func(c *Config) { c.AccessLogRedactHeaders = pointer(common.GetAccessLogRedactHeaders()) }},
+	{env: []string{"APP_ACCESS_LOG_FORMAT"}, apply: func(c *Config) { c.AccessLogFormat = pointer(common.GetAccessLogFormat()) }},
+	{env: []string{"APP_ACCESS_LOG_TEMPLATE"}, apply: func(c *Config) { c.AccessLogTemplate = pointer(common.GetAccessLogTemplate()) }},
+	{env: []string{"APP_PORT"}, apply: func(c *Config) { c.AppPort = pointer(common.GetAppPort()) }},
+	{env: []string{"APP_CORS_ALLOW_CREDENTIALS"}, apply: func(c *Config) { c.CORSAllowCredentials = pointer(common.GetCORSAllowCredentials()) }},
+	{env: []string{"APP_CORS_ALLOW_PRIVATE_NETWORK"}, apply: func(c *Config) { c.CORSAllowPrivateNetwork = pointer(common.GetCORSAllowPrivateNetwork()) }},
+	{env: []string{"APP_CORS_ALLOWED_HEADERS"}, apply: func(c *Config) { c.CORSAllowedHeaders = pointer(common.GetCORSAllowedHeaders()) }},
+	{env: []string{"APP_CORS_ALLOWED_METHODS"}, apply: func(c *Config) { c.CORSAllowedMethods = pointer(common.GetCORSAllowedMethods()) }},
+	{env: []string{"APP_CORS_EXPOSED_HEADERS"}, apply: func(c *Config) { c.CORSExposedHeaders = pointer(common.GetCORSExposedHeaders()) }},
+	{env: []string{"APP_CORS_MAX_AGE"}, apply: func(c *Config) { c.CORSMaxAge = pointer(Duration(common.GetCORSMaxAge())) }},
+	{env: []string{"APP_404_PAGE_FILE_NAME"}, apply: func(c *Config) { c.Error404FilePath = pointer(common.Get404PageFileName()) }},
+	{env: []string{"APP_HANDLE_GZIP"}, apply: func(c *Config) { c.GzipEnabled = pointer(common.GetEnableGZIP()) }},
+	{env: []string{"APP_HTTP_ALLOWED_ORIGINS"}, apply: func(c *Config) {
+		origins, err := common.GetHTTPAllowedOrigins()
+		if err != nil {
+			slog.Error("failed to load allowed http origins", "error", err)
+		}
+		c.HTTPAllowedOrigins = &origins
+	}},
+	{env: []string{"APP_HTTPS_PORT"}, apply: func(c *Config) { c.HTTPSPort = pointer(common.GetAppHTTPSPort()) }},
+	{env: []string{"APP_ENABLE_HTTPS"}, apply: func(c *Config) { c.HTTPSPortEnabled = pointer(common.GetAppEnableHTTPS()) }},
+	{env: []string{"APP_H2C_ENABLED"}, apply: func(c *Config) { c.H2CEnabled = pointer(common.GetH2CEnabled()) }},
+	{env: []string{"APP_H2C_MAX_CONCURRENT_STREAMS"}, apply: func(c *Config) { c.H2CMaxConcurrentStreams = pointer(common.GetH2CMaxConcurrentStreams()) }},
+	{env: []string{"APP_H2C_MAX_STREAM_BUFFER"}, apply: func(c *Config) { c.H2CMaxStreamBuffer = pointer(common.GetH2CMaxStreamBuffer()) }},
+	{env: []string{"APP_HTTP3_ENABLED"}, apply: func(c *Config) { c.HTTP3Enabled = pointer(common.GetAppHTTP3Enabled()) }},
+	{env: []string{"APP_HTTPS_REDIRECT"}, apply: func(c *Config) { c.HTTPSRedirect = pointer(common.GetAppHTTPSRedirect()) }},
+	{env: []string{"APP_HTTPS_REDIRECT_ORIGIN"}, apply: func(c *Config) { c.HTTPSRedirectOrigin = pointer(common.GetAppHTTPSRedirectOrigin()) }},
+	{env: []string{"APP_HSTS_MAX_AGE"}, apply: func(c *Config) { c.HSTSMaxAge = pointer(Duration(common.GetHSTSMaxAge())) }},
+	{env: []string{"APP_HSTS_INCLUDE_SUBDOMAINS"}, apply: func(c *Config) { c.HSTSIncludeSubDomains = pointer(common.GetHSTSIncludeSubDomains()) }},
+	{env: []string{"APP_HSTS_PRELOAD"}, apply: func(c *Config) { c.HSTSPreload = pointer(common.GetHSTSPreload()) }},
+	{env: []string{"APP_HEADER_SET_ENABLE"}, apply: func(c *Config) { c.HeaderMapEnabled = pointer(common.GetHeaderSetEnable()) }},
+	{env: []string{"APP_HEADER_MAP_PATH"}, apply: func(c *Config) { c.HeaderMapPath = pointer(common.GetHeaderMapPath()) }},
+	{env: []string{"APP_HEALTH_PORT"}, apply: func(c *Config) { c.HealthPort = pointer(common.GetAppHealthPort()) }},
+	{env: []string{"APP_HEALTH_PORT_ENABLED"}, apply: func(c *Config) { c.HealthPortEnabled = pointer(common.GetAppHealthPortEnabled()) }},
+	{env: []string{"APP_IDLE_TIMEOUT"}, apply: func(c *Config) { c.IdleTimeout = pointer(Duration(common.GetIdleTimeout())) }},
+	{env: []string{"APP_LOG_FORMAT"}, apply: func(c *Config) { c.LogFormat = pointer(common.GetLogFormat()) }},
+	{env: []string{"APP_LOG_LEVEL"}, apply: func(c *Config) { c.LogLevel = pointer(common.GetLogLevel()) }},
+	{env: []string{"APP_MAX_CONNECTIONS"}, apply: func(c *Config) { c.MaxConnections = pointer(common.GetMaxConnections()) }},
+	{env: []string{"APP_MAX_CONNECTIONS_PER_IP"}, apply: func(c *Config) { c.MaxConnectionsPerIP = pointer(common.GetMaxConnectionsPerIP()) }},
+	{env: []string{"APP_MAX_HEADER_BYTES"}, apply: func(c *Config) { c.MaxHeaderBytes = pointer(common.GetMaxHeaderBytes()) }},
+	{env: []string{"APP_PORT_METRICS"}, apply: func(c *Config) { c.MetricsPort = pointer(common.GetAppMetricsPort()) }},
+	{env: []string{"APP_METRICS_ENABLED"}, apply: func(c *Config) { c.MetricsPortEnabled = pointer(common.GetAppMetricsEnabled()) }},
+	{env: []string{"APP_HTTP_TRUSTED_PROXIES"}, apply: func(c *Config) { c.TrustedProxies = pointer(common.GetTrustedProxies()) }},
+	{env: []string{"APP_READ_HEADER_TIMEOUT"}, apply: func(c *Config) { c.ReadHeaderTimeout = pointer(Duration(common.GetReadHeaderTimeout())) }},
+	{env: []string{"APP_READ_TIMEOUT"}, apply: func(c *Config) { c.ReadTimeout = pointer(Duration(common.GetReadTimeout())) }},
+	{env: []string{"APP_PROXY_PROTOCOL_ENABLED"}, apply: func(c *Config) { c.ProxyProtocolEnabled = pointer(common.GetProxyProtocolEnabled()) }},
+	{env: []string{"APP_PROXY_PROTOCOL_TRUSTED_CIDRS"}, apply: func(c *Config) { c.ProxyProtocolTrustedCIDRs = pointer(common.GetProxyProtocolTrustedCIDRs()) }},
+	{env: []string{"APP_HTTP_REAL_IP_HEADER"}, apply: func(c *Config) { c.RealIPHeader = pointer(common.GetAppRealIPHeader()) }},
+	{env: []string{"APP_REDIRECT_ROUTES_ENABLED"}, apply: func(c *Config) { c.RedirectRoutesEnabled = pointer(common.GetRedirectRoutesEnabled()) }},
+	{env: []string{"APP_REDIRECT_ROUTES_PATH"}, apply: func(c *Config) { c.RedirectRoutesPath = pointer(common.GetRedirectRoutesPath()) }},
+	{env: []string{"APP_RELOAD_INTERVAL"}, apply: func(c *Config) { c.ReloadInterval = pointer(Duration(common.GetReloadInterval())) }},
+	{env: []string{"APP_SERVE_FOLDER", "KO_DATA_PATH"}, apply: func(c *Config) { c.ServeFolder = pointer(common.GetServeFolder()) }},
+	{env: []string{"APP_SHUTDOWN_GRACE_TIMEOUT"}, apply: func(c *Config) { c.ShutdownGraceTimeout = pointer(Duration(common.GetShutdownGraceTimeout())) }},
+	{env: []string{"APP_SHUTDOWN_PRE_STOP_DELAY"}, apply: func(c *Config) { c.ShutdownPreStopDelay = pointer(Duration(common.GetShutdownPreStopDelay())) }},
+	{env: []string{"APP_HTTPS_CRT_DIR"}, apply: func(c *Config) { c.TLSCertDir = pointer(common.GetAppHTTPSCrtDir()) }},
+	{env: []string{"APP_HTTPS_CRT_PATH"}, apply: func(c *Config) { c.TLSCertPath = pointer(common.GetAppHTTPSCrtPath()) }},
+	{env: []string{"APP_HTTPS_CLIENT_AUTH"}, apply: func(c *Config) { c.TLSClientAuth = pointer(common.GetAppHTTPSClientAuth()) }},
+	{env: []string{"APP_HTTPS_CLIENT_CA_PATHS"}, apply: func(c *Config) { c.TLSClientCAPaths = pointer(common.GetAppHTTPSClientCAPaths()) }},
+	{env: []string{"APP_HTTPS_KEY_PATH"}, apply: func(c *Config) { c.TLSKeyPath = pointer(common.GetAppHTTPSKeyPath()) }},
+	{env: []string{"APP_HTTPS_REJECT_UNKNOWN_SNI"}, apply: func(c *Config) { c.TLSRejectUnknownSNI = pointer(common.GetAppHTTPSRejectUnknownSNI()) }},
+	{env: []string{"APP_HTTPS_TLS_PRESET"}, apply: func(c *Config) { c.TLSPreset = pointer(common.GetAppHTTPSTLSPreset()) }},
+	{env: []string{"APP_HTTPS_MIN_VERSION"}, apply: func(c *Config) { c.TLSMinVersion = pointer(common.GetAppHTTPSMinVersion()) }},
+	{env: []string{"APP_HTTPS_MAX_VERSION"}, apply: func(c *Config) { c.TLSMaxVersion = pointer(common.GetAppHTTPSMaxVersion()) }},
+	{env: []string{"APP_HTTPS_CIPHER_SUITES"}, apply: func(c *Config) { c.TLSCipherSuites = pointer(common.GetAppHTTPSCipherSuites()) }},
+	{env: []string{"APP_HTTPS_CURVES"}, apply: func(c *Config) { c.TLSCurves = pointer(common.GetAppHTTPSCurves()) }},
+	{env: []string{"APP_HTTPS_DISABLE_SESSION_TICKETS"}, apply: func(c *Config) { c.TLSSessionTicketsDisabled = pointer(common.GetAppHTTPSDisableSessionTickets()) }},
+	{env: []string{"APP_HTTPS_ALPN"}, apply: func(c *Config) { c.TLSALPN = pointer(common.GetAppHTTPSALPN()) }},
+	{env: []string{"APP_TEMPLATE_MAP_PATH"}, apply: func(c *Config) { c.TemplateMapPath = pointer(common.GetTemplateMapPath()) }},
+	{env: []string{"APP_UNIX_SOCKET_GROUP"}, apply: func(c *Config) { c.UnixSocketGroup = pointer(common.GetUnixSocketGroup()) }},
+	{env: []string{"APP_UNIX_SOCKET_MODE"}, apply: func(c *Config) { c.UnixSocketMode = pointer(common.GetUnixSocketMode()) }},
+	{env: []string{"APP_UNIX_SOCKET_USER"}, apply: func(c *Config) { c.UnixSocketUser = pointer(common.GetUnixSocketUser()) }},
+	{env: []string{"APP_VUEJS_HISTORY_MODE"}, apply: func(c *Config) { c.VueJSHistoryMode = pointer(common.GetVuejsHistoryMode()) }},
+	{env: []string{"APP_WRITE_TIMEOUT"}, apply: func(c *Config) { c.WriteTimeout = pointer(Duration(common.GetWriteTimeout())) }},
+}
+
+func pointer[V any](input V) *V {
+	return &input
+}
+
+// ConfigFromEnv returns the configuration from environment variables.
+// When onlySet is true, only variables which are set are included, otherwise defaults are included too
+func ConfigFromEnv(onlySet bool) *Config {
+	cfg := &Config{}
+	for _, s := range envSettings {
+		set := false
+		for _, e := range s.env {
+			if os.Getenv(e) != "" {
+				set = true
+			}
+		}
+		if set || !onlySet {
+			s.apply(cfg)
+		}
+	}
+	return cfg
+}
+
+// LoadConfigFile loads a YAML or JSON config file, erroring on unknown fields
+func LoadConfigFile(path string) (*Config, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(file, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", newFileError(path, file, err))
+	}
+	return cfg, nil
+}
+
+// ConfigFromDotfile returns the configuration permitted to be set by a dotfile
+func ConfigFromDotfile(dotfile *common.DotfileConfig) *Config {
+	cfg := &Config{}
+	if dotfile.HistoryModeSet() {
+		cfg.VueJSHistoryMode = &dotfile.HistoryMode
+	}
+	if dotfile.Error404FilePath != "" {
+		cfg.Error404FilePath = &dotfile.Error404FilePath
+	}
+	if dotfile.HeaderMap != nil {
+		cfg.HeaderMap = &dotfile.HeaderMap
+		cfg.HeaderMapEnabled = pointer(true)
+	}
+	if dotfile.RedirectRoutes != nil {
+		cfg.RedirectRoutes = &dotfile.RedirectRoutes
+	}
+	if dotfile.TemplateMap != nil {
+		cfg.TemplateMap = &dotfile.TemplateMap
+	}
+	return cfg
+}
+
+// addConfig adds configuration from a source, to be applied in order of precedence
+func (w *WebServer) addConfig(source Source, cfg *Config) {
+	w.configLayers = append(w.configLayers, configLayer{source: source, config: cfg})
+}
+
+// resolveConfig applies the configuration layers in order of precedence
+func (w *WebServer) resolveConfig() {
+	configPath := w.configPath
+	if configPath == "" {
+		configPath = w.envConfigPath
+	}
+	if configPath != "" {
+		cfg, err := LoadConfigFile(configPath)
+		if err != nil {
+			w.configErr = err
+		} else {
+			w.addConfig(SourceConfigFile, cfg)
+		}
+	}
+	sort.SliceStable(w.configLayers, func(i, j int) bool {
+		return w.configLayers[i].source < w.configLayers[j].source
+	})
+	for _, l := range w.configLayers {
+		w.applyConfig(l.source, l.config)
+	}
+	w.configLayers = nil
+	w.HTTPPort = w.AppPort
+	if err := w.validateLogging(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if _, err := realip.ParseTrustedProxies(w.TrustedProxies); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateProxyProtocol(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateListeners(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateCORS(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateTLS(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateTLSPolicy(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateHTTPS(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateHTTP3(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateH2C(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+	if err := w.validateACME(); err != nil {
+		w.configErr = errors.Join(w.configErr, err)
+	}
+}
+
+// validateLogging ensures that the log level and formats are valid
+func (w *WebServer) validateLogging() error {
+	if _, err := logging.NewLogger(io.Discard, w.LogFormat, w.LogLevel); err != nil {
+		return err
+	}
+	if _, err := logging.NewAccessLogger(io.Discard, w.AccessLogFormat, w.AccessLogTemplate, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// applyConfig sets the WebServer fields which are set in the configuration, recording their source
+func (w *WebServer) applyConfig(source Source, cfg *Config) {
+	if w.configSources == nil {
+		w.configSources = map[string]Source{}
+	}
+	cv := reflect.ValueOf(cfg).Elem()
+	wv := reflect.ValueOf(w).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		field := cv.Field(i)
+		if field.IsNil() {
+			continue
+		}
+		target := wv.FieldByName(cv.Type().Field(i).Name)
+		target.Set(field.Elem().Convert(target.Type()))
+		w.configSources[configFieldName(cv.Type().Field(i))] = source
+	}
+}
+
+// configFieldName returns the name of a config field, as written in a config file
+func configFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// ConfigSources returns where each configuration value came from, by config file field name
+func (w *WebServer) ConfigSources() map[string]Source {
+	sources := map[string]Source{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name := configFieldName(t.Field(i))
+		sources[name] = w.configSources[name]
+	}
+	return sources
+}
+
+// secretConfigFields are the settings whose values are personal or secret, which aren't logged
+var secretConfigFields = map[string]bool{
+	"acmeEmail": true,
+}
+
+// logConfigSources logs each effective configuration value and where it came from.
+// Values which are set are logged at info, and those left at their defaults at debug.
+// The values of maps, such as headers and templated env, and of secrets are left out
+func (w *WebServer) logConfigSources(logger *slog.Logger) {
+	t := reflect.TypeOf(Config{})
+	wv := reflect.ValueOf(w).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := configFieldName(f)
+		level := slog.LevelInfo
+		if w.configSources[name] == SourceDefault {
+			level = slog.LevelDebug
+		}
+		value := wv.FieldByName(f.Name)
+		if value.Kind() == reflect.Map || secretConfigFields[name] {
+			logger.Log(context.Background(), level, "config", "name", name, "source", w.configSources[name].String())
+			continue
+		}
+		logger.Log(context.Background(), level, "config", "name", name, "value", value.Interface(), "source", w.configSources[name].String())
+	}
+}
@@ -0,0 +1,557 @@
+package httpserver
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
+)
+
+func TestNew_configPrecedence(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		configFile  string
+		dotfile     string
+		opts        []Option
+		findValue   func(*WebServer) any
+		want        any
+		wantSources map[string]Source
+	}{
+		{
+			name:       "config file overrides defaults",
+			configFile: "appPort: :8125\ngzipEnabled: false\nshutdownGraceTimeout: 10s\n",
+			opts:       []Option{FromEnv()},
+			findValue: func(ws *WebServer) any {
+				return []any{ws.AppPort, ws.GzipEnabled, ws.ShutdownGraceTimeout}
+			},
+			want: []any{":8125", false, 10 * time.Second},
+			wantSources: map[string]Source{
+				"appPort":              SourceConfigFile,
+				"gzipEnabled":          SourceConfigFile,
+				"shutdownGraceTimeout": SourceConfigFile,
+				"realIPHeader":         SourceDefault,
+			},
+		},
+		{
+			name:       "env overrides config file",
+			env:        map[string]string{"APP_PORT": ":8126"},
+			configFile: "appPort: :8125\n",
+			opts:       []Option{FromEnv()},
+			findValue: func(ws *WebServer) any {
+				return ws.AppPort
+			},
+			want:        ":8126",
+			wantSources: map[string]Source{"appPort": SourceEnv},
+		},
+		{
+			name:       "options override env, regardless of order",
+			env:        map[string]string{"APP_PORT": ":8126"},
+			configFile: "appPort: :8125\n",
+			opts:       []Option{WithAppPort(":8127"), FromEnv()},
+			findValue: func(ws *WebServer) any {
+				return ws.AppPort
+			},
+			want:        ":8127",
+			wantSources: map[string]Source{"appPort": SourceOption},
+		},
+		{
+			name: "flags override options",
+			opts: []Option{
+				FromEnv(),
+				WithConfig(SourceFlag, &Config{AppPort: pointer(":8128")}),
+				WithAppPort(":8127"),
+			},
+			findValue: func(ws *WebServer) any {
+				return ws.AppPort
+			},
+			want:        ":8128",
+			wantSources: map[string]Source{"appPort": SourceFlag},
+		},
+		{
+			name:    "dotfile overrides env for the fields it sets",
+			env:     map[string]string{"APP_VUEJS_HISTORY_MODE": "true", "APP_404_PAGE_FILE_NAME": "404.html"},
+			dotfile: "historyMode: false\n",
+			opts:    []Option{FromEnv()},
+			findValue: func(ws *WebServer) any {
+				return []any{ws.VueJSHistoryMode, ws.Error404FilePath}
+			},
+			want: []any{false, "404.html"},
+			wantSources: map[string]Source{
+				"historyMode":      SourceDotfile,
+				"error404FilePath": SourceEnv,
+			},
+		},
+		{
+			name:    "dotfile leaves history mode alone when unset",
+			env:     map[string]string{"APP_VUEJS_HISTORY_MODE": "true"},
+			dotfile: "error404FilePath: not-found.html\n",
+			opts:    []Option{FromEnv()},
+			findValue: func(ws *WebServer) any {
+				return []any{ws.VueJSHistoryMode, ws.Error404FilePath}
+			},
+			want: []any{true, "not-found.html"},
+			wantSources: map[string]Source{
+				"historyMode":      SourceEnv,
+				"error404FilePath": SourceDotfile,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		// NOTE sets env and cannot be parallelised
+		t.Run(tt.name, func(t *testing.T) {
+			serveFolder := t.TempDir()
+			env := map[string]string{"APP_SERVE_FOLDER": serveFolder}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			if tt.configFile != "" {
+				configPath := path.Join(t.TempDir(), "ghs.yaml")
+				if err := os.WriteFile(configPath, []byte(tt.configFile), 0644); err != nil {
+					t.Fatal(err)
+				}
+				env["APP_CONFIG_PATH"] = configPath
+			}
+			if tt.dotfile != "" {
+				if err := os.WriteFile(path.Join(serveFolder, ".ghs.yaml"), []byte(tt.dotfile), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+			ws := New(tt.opts...)
+			ws.loadConfig()
+			if ws.configErr != nil {
+				t.Fatalf("New() config error = %v", ws.configErr)
+			}
+			if got := tt.findValue(ws); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+			sources := ws.ConfigSources()
+			for name, want := range tt.wantSources {
+				if got := sources[name]; got != want {
+					t.Errorf("ConfigSources()[%v] = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name:    "yaml",
+			content: "appPort: :8125\nhttpsPortEnabled: true\nshutdownPreStopDelay: 2s\nheaderMap:\n  X-Abc: [a]\n",
+			want: &Config{
+				AppPort:              pointer(":8125"),
+				HTTPSPortEnabled:     pointer(true),
+				ShutdownPreStopDelay: pointer(Duration(2 * time.Second)),
+				HeaderMap:            &map[string][]string{"X-Abc": {"a"}},
+			},
+		},
+		{
+			name:    "json",
+			content: `{"serveFolder": "./site", "httpAllowedOrigins": ["https://example.com"]}`,
+			want: &Config{
+				ServeFolder:        pointer("./site"),
+				HTTPAllowedOrigins: &[]string{"https://example.com"},
+			},
+		},
+		{
+			name:    "cors rules",
+			content: "corsRules:\n  - path: /fonts/\n    allowedOrigins: ['*']\n    maxAge: 1h\n",
+			want: &Config{
+				CORSRules: &[]CORSRule{{
+					Path:       "/fonts/",
+					CORSPolicy: CORSPolicy{AllowedOrigins: []string{"*"}, MaxAge: pointer(Duration(time.Hour))},
+				}},
+			},
+		},
+		{
+			name:    "unknown field",
+			content: "appPrt: :8125\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			content: "shutdownGraceTimeout: soon\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			configPath := path.Join(t.TempDir(), "ghs.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadConfigFile(configPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadConfigFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_invalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "invalid log level",
+			opts: []Option{WithLogging("loud", "text")},
+		},
+		{
+			name: "invalid access log format",
+			opts: []Option{WithAccessLog("xml", "")},
+		},
+		{
+			name: "invalid access log template",
+			opts: []Option{WithAccessLog("template", "{{.Status")},
+		},
+		{
+			name: "invalid trusted proxy",
+			opts: []Option{WithTrustedProxies("10.0.0.0/33")},
+		},
+		{
+			name: "proxy protocol without trusted CIDRs",
+			opts: []Option{WithProxyProtocol()},
+		},
+		{
+			name: "invalid proxy protocol trusted CIDR",
+			opts: []Option{WithProxyProtocol("example.com")},
+		},
+		{
+			name: "unix socket without a path",
+			opts: []Option{WithAppPort("unix:")},
+		},
+		{
+			name: "invalid unix socket mode",
+			opts: []Option{WithUnixSocketOwnership("rw-rw----", "", "")},
+		},
+		{
+			name: "listener timeouts of an unknown listener",
+			opts: []Option{WithListenerTimeouts(map[string]ServerTimeouts{"ftp": {}})},
+		},
+		{
+			name: "negative max connections",
+			opts: []Option{WithMaxConnections(-1, 0)},
+		},
+		{
+			name: "CORS credentials from every origin",
+			opts: []Option{WithCORS(CORSPolicy{AllowCredentials: pointer(true)})},
+		},
+		{
+			name: "CORS rule credentials from every origin",
+			opts: []Option{
+				WithCORS(CORSPolicy{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: pointer(true)}),
+				WithCORSRules(CORSRule{Path: "/fonts/", CORSPolicy: CORSPolicy{AllowedOrigins: []string{"*"}}}),
+			},
+		},
+		{
+			name: "CORS rule without a path",
+			opts: []Option{WithCORSRules(CORSRule{Path: "fonts"})},
+		},
+		{
+			name: "ACME without hostnames",
+			opts: []Option{
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true)}),
+				WithACME("https://acme.invalid/directory", "./acme-cache", ""),
+			},
+		},
+		{
+			name: "ACME without HTTPS",
+			opts: []Option{WithACME("https://acme.invalid/directory", "./acme-cache", "", "example.com")},
+		},
+		{
+			name: "TLS certificate without a key",
+			opts: []Option{WithTLSCertificates("", false, TLSCertificate{CertPath: "tls.crt"})},
+		},
+		{
+			name: "negative h2c max concurrent streams",
+			opts: []Option{WithH2C(-1, 0)},
+		},
+		{
+			name: "h2c max stream buffer above 2GiB",
+			opts: []Option{WithH2C(0, 1<<31)},
+		},
+		{
+			name: "HTTP/3 without HTTPS",
+			opts: []Option{WithHTTP3()},
+		},
+		{
+			name: "HTTP/3 on a Unix socket",
+			opts: []Option{
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("unix:/run/ghs/https.sock")}),
+				WithHTTP3(),
+			},
+		},
+		{
+			name: "HTTP/3 without TLS 1.3",
+			opts: []Option{
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true)}),
+				WithTLSPolicy(TLSPolicy{MaxVersion: "1.2"}),
+				WithHTTP3(),
+			},
+		},
+		{
+			name: "HTTPS redirect without HTTPS",
+			opts: []Option{WithHTTPSRedirect("")},
+		},
+		{
+			name: "HTTPS redirect to a Unix socket",
+			opts: []Option{
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("unix:/run/ghs/https.sock")}),
+				WithHTTPSRedirect(""),
+			},
+		},
+		{
+			name: "HTTPS redirect origin over HTTP",
+			opts: []Option{WithHTTPSRedirect("http://example.com")},
+		},
+		{
+			name: "HTTPS redirect origin with a path",
+			opts: []Option{WithHTTPSRedirect("https://example.com/app")},
+		},
+		{
+			name: "negative HSTS max age",
+			opts: []Option{WithHSTS(-time.Second)},
+		},
+		{
+			name: "HSTS preload without subdomains",
+			opts: []Option{WithHSTS(2 * 365 * 24 * time.Hour), WithHSTSPreload(true)},
+		},
+		{
+			name: "unknown TLS preset",
+			opts: []Option{WithTLSPolicy(TLSPolicy{Preset: "old"})},
+		},
+		{
+			name: "unknown TLS version",
+			opts: []Option{WithTLSPolicy(TLSPolicy{MinVersion: "TLS1.2"})},
+		},
+		{
+			name: "minimum TLS version above the maximum",
+			opts: []Option{WithTLSPolicy(TLSPolicy{MinVersion: "1.3", MaxVersion: "1.2"})},
+		},
+		{
+			name: "insecure cipher suite",
+			opts: []Option{WithTLSPolicy(TLSPolicy{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}})},
+		},
+		{
+			name: "TLS 1.3 cipher suite",
+			opts: []Option{WithTLSPolicy(TLSPolicy{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}})},
+		},
+		{
+			name: "unknown curve",
+			opts: []Option{WithTLSPolicy(TLSPolicy{Curves: []string{"secp256k1"}})},
+		},
+		{
+			name: "unknown ALPN protocol",
+			opts: []Option{WithTLSPolicy(TLSPolicy{ALPN: []string{"h3"}})},
+		},
+		{
+			name: "HTTP/2 without its required cipher suite",
+			opts: []Option{WithTLSPolicy(TLSPolicy{ALPN: []string{"h2"}, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}})},
+		},
+		{
+			name: "unknown TLS client auth",
+			opts: []Option{WithTLSClientAuth("always", []string{"ca.crt"})},
+		},
+		{
+			name: "verifying client certificates without CA bundles",
+			opts: []Option{WithTLSClientAuth(ClientAuthRequireAndVerify, nil)},
+		},
+		{
+			name: "TLS client auth rules without verifying",
+			opts: []Option{WithTLSClientAuth(ClientAuthRequireAny, []string{"ca.crt"}, TLSClientAuthRule{Path: "/admin", Required: true})},
+		},
+		{
+			name: "TLS client auth rule without a leading slash",
+			opts: []Option{WithTLSClientAuth(ClientAuthVerifyIfGiven, []string{"ca.crt"}, TLSClientAuthRule{Path: "admin", Required: true})},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if errs := New(tt.opts...).Validate(); len(errs) == 0 {
+				t.Errorf("New().Validate() wants an error")
+			}
+		})
+	}
+}
+
+func TestDuration_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Duration
+		wantErr bool
+	}{
+		{
+			name:  "string",
+			input: `"15s"`,
+			want:  Duration(15 * time.Second),
+		},
+		{
+			name:  "zero",
+			input: `"0s"`,
+		},
+		{
+			name:    "bare number",
+			input:   `15`,
+			wantErr: true,
+		},
+		{
+			name:    "string without a unit",
+			input:   `"15"`,
+			wantErr: true,
+		},
+		{
+			name:    "boolean",
+			input:   `true`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got Duration
+			err := got.UnmarshalJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Duration.UnmarshalJSON(%v) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Duration.UnmarshalJSON(%v) = %v, want %v", tt.input, time.Duration(got), time.Duration(tt.want))
+			}
+		})
+	}
+}
+
+func TestWebServer_logConfigSources(t *testing.T) {
+	w := New(WithAppPort(":8081"))
+	buf := &bytes.Buffer{}
+	w.logConfigSources(slog.New(slog.NewTextHandler(buf, nil)))
+	if !strings.Contains(buf.String(), "name=appPort value=:8081 source=option") {
+		t.Errorf("logConfigSources() = %v, want the app port set by option", buf.String())
+	}
+	if strings.Contains(buf.String(), "source=default") {
+		t.Errorf("logConfigSources() = %v, want values left at their defaults not logged at info", buf.String())
+	}
+
+	w = New(
+		WithACME("https://acme.invalid/directory", t.TempDir(), "admin@example.com", "example.com"),
+		WithHeaderMap(map[string][]string{"X-Token": {"secret-header"}}),
+		WithTemplateMap(map[string]string{"TOKEN": "secret-template"}),
+	)
+	buf.Reset()
+	w.logConfigSources(slog.New(slog.NewTextHandler(buf, nil)))
+	for _, secret := range []string{"admin@example.com", "secret-header", "secret-template"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("logConfigSources() = %v, want %v not logged", buf.String(), secret)
+		}
+	}
+	if !strings.Contains(buf.String(), "name=headerMap source=option") {
+		t.Errorf("logConfigSources() = %v, want the header map's source logged", buf.String())
+	}
+}
+
+func TestEnvSettings(t *testing.T) {
+	// an env value for each type of setting, and the value it's read as
+	type sample struct {
+		env  string
+		want any
+	}
+	samples := map[reflect.Type]sample{
+		reflect.TypeOf(""):          {env: "value", want: "value"},
+		reflect.TypeOf(false):       {env: "true", want: true},
+		reflect.TypeOf(0):           {env: "7", want: 7},
+		reflect.TypeOf(Duration(0)): {env: "7s", want: Duration(7 * time.Second)},
+		reflect.TypeOf([]string{}):  {env: "a, b", want: []string{"a", "b"}},
+	}
+	// settings which are read as more than their value
+	overrides := map[string]sample{
+		"APP_HTTP_ALLOWED_ORIGINS": {env: "https://a.example.com,https://b.example.com", want: []string{"https://a.example.com", "https://b.example.com"}},
+	}
+	// values for each type of setting which aren't read, leaving the default
+	invalid := map[reflect.Type]string{
+		reflect.TypeOf(0):           "seven",
+		reflect.TypeOf(Duration(0)): "seven seconds",
+	}
+	// the values of some settings when unset
+	defaults := map[string]any{
+		"APP_CORS_ALLOWED_METHODS": []string{http.MethodGet},
+		"APP_HANDLE_GZIP":          true,
+		"APP_PORT":                 common.DefaultAppPort,
+		"APP_READ_TIMEOUT":         Duration(15 * time.Second),
+	}
+	for _, s := range envSettings {
+		s := s
+		// NOTE sets env and cannot be parallelised
+		t.Run(s.env[0], func(t *testing.T) {
+			for _, e := range s.env {
+				t.Setenv(e, "")
+			}
+			unset := &Config{}
+			s.apply(unset)
+			field := -1
+			uv := reflect.ValueOf(unset).Elem()
+			for i := 0; i < uv.NumField(); i++ {
+				if !uv.Field(i).IsNil() {
+					field = i
+				}
+			}
+			if field < 0 {
+				t.Fatalf("setting %v doesn't set a config field", s.env)
+			}
+			want, ok := overrides[s.env[0]]
+			if !ok {
+				want, ok = samples[uv.Field(field).Type().Elem()]
+			}
+			if !ok {
+				t.Fatalf("no sample value of %v for %v", uv.Field(field).Type(), s.env)
+			}
+			unsetValue := uv.Field(field).Elem().Interface()
+			if d, ok := defaults[s.env[0]]; ok && !reflect.DeepEqual(unsetValue, d) {
+				t.Errorf("%v is read as %#v when unset, want %#v", s.env[0], unsetValue, d)
+			}
+			for _, e := range s.env {
+				t.Setenv(e, want.env)
+				cfg := &Config{}
+				s.apply(cfg)
+				if got := reflect.ValueOf(cfg).Elem().Field(field).Elem().Interface(); !reflect.DeepEqual(got, want.want) {
+					t.Errorf("%v=%v is read as %#v, want %#v", e, want.env, got, want.want)
+				}
+				if v, ok := invalid[uv.Field(field).Type().Elem()]; ok {
+					t.Setenv(e, v)
+					cfg := &Config{}
+					s.apply(cfg)
+					if got := reflect.ValueOf(cfg).Elem().Field(field).Elem().Interface(); !reflect.DeepEqual(got, unsetValue) {
+						t.Errorf("%v=%v is read as %#v, want the default %#v", e, v, got, unsetValue)
+					}
+				}
+				t.Setenv(e, "")
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/cors"
+)
+
+// CORSPolicy is a CORS policy for a path prefix. Unset fields use the policy for every path
+type CORSPolicy struct {
+	AllowedOrigins      []string  `json:"allowedOrigins,omitempty"`
+	AllowedMethods      []string  `json:"allowedMethods,omitempty"`
+	AllowedHeaders      []string  `json:"allowedHeaders,omitempty"`
+	ExposedHeaders      []string  `json:"exposedHeaders,omitempty"`
+	AllowCredentials    *bool     `json:"allowCredentials,omitempty"`
+	MaxAge              *Duration `json:"maxAge,omitempty"`
+	AllowPrivateNetwork *bool     `json:"allowPrivateNetwork,omitempty"`
+}
+
+// CORSRule is the CORS policy for requests with paths under the path, by whole segments
+type CORSRule struct {
+	Path string `json:"path"`
+	CORSPolicy
+}
+
+// corsOptions returns the options for the policy for every path
+func (w *WebServer) corsOptions() cors.Options {
+	return cors.Options{
+		AllowedOrigins:      w.HTTPAllowedOrigins,
+		AllowedMethods:      w.CORSAllowedMethods,
+		AllowedHeaders:      w.CORSAllowedHeaders,
+		ExposedHeaders:      w.CORSExposedHeaders,
+		AllowCredentials:    w.CORSAllowCredentials,
+		MaxAge:              int(w.CORSMaxAge / time.Second),
+		AllowPrivateNetwork: w.CORSAllowPrivateNetwork,
+	}
+}
+
+// options returns the options of the policy, with unset fields from base
+func (p CORSPolicy) options(base cors.Options) cors.Options {
+	o := base
+	if p.AllowedOrigins != nil {
+		o.AllowedOrigins = p.AllowedOrigins
+	}
+	if p.AllowedMethods != nil {
+		o.AllowedMethods = p.AllowedMethods
+	}
+	if p.AllowedHeaders != nil {
+		o.AllowedHeaders = p.AllowedHeaders
+	}
+	if p.ExposedHeaders != nil {
+		o.ExposedHeaders = p.ExposedHeaders
+	}
+	if p.AllowCredentials != nil {
+		o.AllowCredentials = *p.AllowCredentials
+	}
+	if p.MaxAge != nil {
+		o.MaxAge = int(time.Duration(*p.MaxAge) / time.Second)
+	}
+	if p.AllowPrivateNetwork != nil {
+		o.AllowPrivateNetwork = *p.AllowPrivateNetwork
+	}
+	return o
+}
+
+// corsHandler handles CORS for each request with the rule of the longest matching path,
+// otherwise with the policy for every path
+func (w *WebServer) corsHandler(next http.Handler) http.Handler {
+	base := w.corsOptions()
+	defaultHandler := cors.New(base).Handler(next)
+	if len(w.CORSRules) == 0 {
+		return defaultHandler
+	}
+	type pathHandler struct {
+		path    string
+		handler http.Handler
+	}
+	rules := make([]pathHandler, 0, len(w.CORSRules))
+	for _, r := range w.CORSRules {
+		rules = append(rules, pathHandler{path: r.Path, handler: cors.New(r.options(base)).Handler(next)})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].path) > len(rules[j].path)
+	})
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		for _, rule := range rules {
+			if hasPathPrefix(r.URL.Path, rule.path) {
+				rule.handler.ServeHTTP(rw, r)
+				return
+			}
+		}
+		defaultHandler.ServeHTTP(rw, r)
+	})
+}
+
+// validateCORS ensures that the CORS policies are valid, and that credentials aren't allowed
+// from every origin
+func (w *WebServer) validateCORS() error {
+	var errs []error
+	base := w.corsOptions()
+	errs = append(errs, validateCORSOptions("", base))
+	for _, r := range w.CORSRules {
+		if !strings.HasPrefix(r.Path, "/") {
+			errs = append(errs, fmt.Errorf("invalid CORS rule path '%v', expected a path starting with /", r.Path))
+			continue
+		}
+		errs = append(errs, validateCORSOptions(r.Path, r.options(base)))
+	}
+	return errors.Join(errs...)
+}
+
+// validateCORSOptions ensures that the options of the policy for the path are valid
+func validateCORSOptions(path string, o cors.Options) error {
+	policy := "the CORS policy"
+	if path != "" {
+		policy = fmt.Sprintf("the CORS rule for %v", path)
+	}
+	origins := o.AllowedOrigins
+	if len(origins) == 0 {
+		// every origin is allowed by default
+		origins = []string{"*"}
+	}
+	var errs []error
+	for _, origin := range origins {
+		if origin == "*" {
+			if o.AllowCredentials {
+				errs = append(errs, fmt.Errorf("%v allows credentials from every origin, set the allowed origins", policy))
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			errs = append(errs, fmt.Errorf("invalid origin '%v' in %v, only one wildcard is allowed", origin, policy))
+		}
+	}
+	if o.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("the max age of %v must not be negative", policy))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package httpserver
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebServer_Handler_cors(t *testing.T) {
+	w := New(
+		WithServeFolder(t.TempDir()),
+		WithCORS(CORSPolicy{
+			AllowedOrigins:   []string{"https://*.example.com"},
+			AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+			ExposedHeaders:   []string{"ETag"},
+			AllowCredentials: pointer(true),
+			MaxAge:           pointer(Duration(10 * time.Minute)),
+		}),
+		WithCORSRules(CORSRule{
+			Path: "/fonts/",
+			CORSPolicy: CORSPolicy{
+				AllowedOrigins:   []string{"*"},
+				AllowCredentials: pointer(false),
+			},
+		}, CORSRule{
+			Path:       "/api",
+			CORSPolicy: CORSPolicy{AllowedOrigins: []string{"https://partner.example.org"}},
+		}),
+	)
+	w.accessLogOut = io.Discard
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		origin          string
+		wantAllowOrigin string
+		wantCredentials string
+		wantMaxAge      string
+	}{
+		{
+			name:            "allowed origin",
+			method:          http.MethodGet,
+			path:            "/",
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "https://app.example.com",
+			wantCredentials: "true",
+		},
+		{
+			name:   "origin not allowed",
+			method: http.MethodGet,
+			path:   "/",
+			origin: "https://example.org",
+		},
+		{
+			name:            "preflight",
+			method:          http.MethodOptions,
+			path:            "/",
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "https://app.example.com",
+			wantCredentials: "true",
+			wantMaxAge:      "600",
+		},
+		{
+			name:            "public path rule",
+			method:          http.MethodGet,
+			path:            "/fonts/a.woff2",
+			origin:          "https://example.org",
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "public path rule inherits the policy",
+			method:          http.MethodOptions,
+			path:            "/fonts/a.woff2",
+			origin:          "https://example.org",
+			wantAllowOrigin: "*",
+			wantMaxAge:      "600",
+		},
+		{
+			name:            "path rule",
+			method:          http.MethodGet,
+			path:            "/api/users",
+			origin:          "https://partner.example.org",
+			wantAllowOrigin: "https://partner.example.org",
+			wantCredentials: "true",
+		},
+		{
+			name:   "path sharing the prefix of a path rule",
+			method: http.MethodGet,
+			path:   "/apiv2-internal",
+			origin: "https://partner.example.org",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, req)
+			header := rec.Result().Header
+			if got := header.Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("%v %v Access-Control-Allow-Origin = %q, want %q", tt.method, tt.path, got, tt.wantAllowOrigin)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("%v %v Access-Control-Allow-Credentials = %q, want %q", tt.method, tt.path, got, tt.wantCredentials)
+			}
+			if got := header.Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("%v %v Access-Control-Max-Age = %q, want %q", tt.method, tt.path, got, tt.wantMaxAge)
+			}
+		})
+	}
+}
+
+func TestWebServer_Handler_corsPreflightLogged(t *testing.T) {
+	w := New(
+		WithServeFolder(t.TempDir()),
+		WithCORS(CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}),
+	)
+	buf := &bytes.Buffer{}
+	w.accessLogOut = buf
+	req := httptest.NewRequest(http.MethodOptions, "/preflight", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w.ServeHTTP(httptest.NewRecorder(), req)
+	if !strings.Contains(buf.String(), "/preflight") {
+		t.Errorf("access log = %q, want the preflight answered before the router logged", buf.String())
+	}
+}
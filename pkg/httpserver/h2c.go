@@ -0,0 +1,53 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cServer is the plain HTTP server serving h2c, which also waits for the requests in flight
+// on h2c connections on shutdown, as they're hijacked from the server
+type h2cServer struct {
+	*http.Server
+	requests *requestTracker
+}
+
+func (s *h2cServer) Shutdown(ctx context.Context) error {
+	return errors.Join(s.Server.Shutdown(ctx), s.requests.wait(ctx))
+}
+
+// configureH2C serves HTTP/2 without TLS on the server, with prior knowledge and by upgrading
+// from HTTP/1.1, with the stream limits. h2c connections are sent GOAWAY when the server shuts down
+func (w *WebServer) configureH2C(s *http.Server) *h2cServer {
+	h2s := &http2.Server{
+		MaxConcurrentStreams:     uint32(w.H2CMaxConcurrentStreams),
+		MaxUploadBufferPerStream: int32(w.H2CMaxStreamBuffer),
+		IdleTimeout:              s.IdleTimeout,
+	}
+	// registers sending GOAWAY on shutdown, the TLS config it sets isn't used without TLS
+	if err := http2.ConfigureServer(s, h2s); err != nil {
+		slog.Error("failed to configure h2c", "error", err)
+	}
+	s.TLSConfig = nil
+	requests := &requestTracker{}
+	s.Handler = h2c.NewHandler(requests.handler(s.Handler), h2s)
+	return &h2cServer{Server: s, requests: requests}
+}
+
+// validateH2C ensures that the h2c stream limits are in range
+func (w *WebServer) validateH2C() error {
+	var errs []error
+	if w.H2CMaxConcurrentStreams < 0 || int64(w.H2CMaxConcurrentStreams) > math.MaxUint32 {
+		errs = append(errs, errors.New("the h2c max concurrent streams must be between 0 and 4294967295"))
+	}
+	if w.H2CMaxStreamBuffer < 0 || int64(w.H2CMaxStreamBuffer) > math.MaxInt32 {
+		errs = append(errs, errors.New("the h2c max upload buffer per stream must be between 0 and 2147483647"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,130 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+// listenHTTP serves the WebServer's listeners, returning the address of the HTTP listener
+func listenHTTP(t *testing.T, ws *WebServer) string {
+	t.Helper()
+	ws.accessLogOut = io.Discard
+	ws.build()
+	servers, err := ws.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range servers {
+		s := s
+		go func() { _ = s.server.Serve(s.listener) }()
+	}
+	t.Cleanup(func() {
+		for _, s := range servers {
+			_ = s.server.Shutdown(context.Background())
+		}
+	})
+	for _, s := range servers {
+		if s.name == "http" {
+			return s.listener.Addr().String()
+		}
+	}
+	t.Fatal("no HTTP listener")
+	return ""
+}
+
+func TestWebServer_listen_h2c(t *testing.T) {
+	serveFolder := t.TempDir()
+	if err := os.WriteFile(path.Join(serveFolder, "index.html"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	priorKnowledge := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+
+	t.Run("prior knowledge", func(t *testing.T) {
+		addr := listenHTTP(t, New(WithServeFolder(serveFolder), WithAppPort("127.0.0.1:0"), WithH2C(0, 0)))
+		resp, err := priorKnowledge.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK || string(body) != "hello" {
+			t.Errorf("GET with prior knowledge = %v %v %q, want HTTP/2.0 %v %q", resp.Proto, resp.StatusCode, body, http.StatusOK, "hello")
+		}
+	})
+
+	t.Run("prior knowledge when disabled", func(t *testing.T) {
+		addr := listenHTTP(t, New(WithServeFolder(serveFolder), WithAppPort("127.0.0.1:0")))
+		if resp, err := priorKnowledge.Get("http://" + addr + "/"); err == nil {
+			resp.Body.Close()
+			t.Errorf("GET with prior knowledge = %v, want an error", resp.Proto)
+		}
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		addr := listenHTTP(t, New(WithServeFolder(serveFolder), WithAppPort("127.0.0.1:0"), WithH2C(0, 0)))
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		req := "GET / HTTP/1.1\r\nHost: " + addr + "\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n"
+		if _, err := conn.Write([]byte(req)); err != nil {
+			t.Fatal(err)
+		}
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(status, "HTTP/1.1 101") {
+			t.Errorf("upgrade status = %q, want 101 Switching Protocols", status)
+		}
+	})
+
+	t.Run("stream limits", func(t *testing.T) {
+		addr := listenHTTP(t, New(WithServeFolder(serveFolder), WithAppPort("127.0.0.1:0"), WithH2C(10, 65536)))
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+			t.Fatal(err)
+		}
+		framer := http2.NewFramer(conn, conn)
+		if err := framer.WriteSettings(); err != nil {
+			t.Fatal(err)
+		}
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings, ok := frame.(*http2.SettingsFrame)
+		if !ok {
+			t.Fatalf("first frame = %T, want settings", frame)
+		}
+		if v, _ := settings.Value(http2.SettingMaxConcurrentStreams); v != 10 {
+			t.Errorf("max concurrent streams = %v, want %v", v, 10)
+		}
+		if v, _ := settings.Value(http2.SettingInitialWindowSize); v != 65536 {
+			t.Errorf("initial window size = %v, want %v", v, 65536)
+		}
+	})
+}
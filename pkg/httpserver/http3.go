@@ -0,0 +1,153 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// errPacketListenerAccept is returned when accepting from a packet listener, which is read by its server
+var errPacketListenerAccept = errors.New("connections can't be accepted from a packet listener")
+
+// packetListener is a UDP socket as a listener, for HTTP/3 to be bound, served and
+// passed to an upgraded process along with the other servers
+type packetListener struct {
+	*net.UDPConn
+}
+
+func (l *packetListener) Accept() (net.Conn, error) {
+	return nil, errPacketListenerAccept
+}
+
+func (l *packetListener) Addr() net.Addr {
+	return l.LocalAddr()
+}
+
+// listenPacket listens on a UDP address, for HTTP/3
+func listenPacket(addr string) (net.Listener, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &packetListener{UDPConn: conn}, nil
+}
+
+// requestTracker counts the requests in flight on connections which aren't tracked by an
+// http.Server, such as HTTP/3 and h2c connections, for shutting down to wait for them
+type requestTracker struct {
+	inFlight atomic.Int64
+}
+
+// handler counts the requests served by next
+func (t *requestTracker) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		t.inFlight.Add(1)
+		defer t.inFlight.Add(-1)
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// wait waits until no requests are in flight, or the context is done
+func (t *requestTracker) wait(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for t.inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// http3Server serves HTTP/3 over QUIC on a packet listener.
+// On shutdown, it waits for the requests in flight before closing the connections
+type http3Server struct {
+	server   *http3.Server
+	requests requestTracker
+
+	mu       sync.Mutex
+	listener *packetListener
+}
+
+// newHTTP3Server returns a server for HTTP/3 on the HTTPS port, with the TLS config and
+// timeouts and limits of HTTPS
+func (w *WebServer) newHTTP3Server(handler http.Handler) *http3Server {
+	s := &http3Server{}
+	settings := &http.Server{}
+	w.configureServer("https")(settings)
+	s.server = &http3.Server{
+		TLSConfig: w.TLSConfig,
+		// 0-RTT is left disabled, as early data can be replayed
+		QuicConfig:     &quic.Config{MaxIdleTimeout: settings.IdleTimeout},
+		MaxHeaderBytes: settings.MaxHeaderBytes,
+	}
+	s.server.Handler = s.requests.handler(handler)
+	return s
+}
+
+// Serve serves HTTP/3 on the packet listener until shut down
+func (s *http3Server) Serve(l net.Listener) error {
+	pl, ok := l.(*packetListener)
+	if !ok {
+		return fmt.Errorf("HTTP/3 requires a packet listener, not %T", l)
+	}
+	s.mu.Lock()
+	s.listener = pl
+	s.mu.Unlock()
+	return s.server.Serve(pl.UDPConn)
+}
+
+// Shutdown waits for the requests in flight, then closes the connections and the packet listener
+func (s *http3Server) Shutdown(ctx context.Context) error {
+	err := errors.Join(s.requests.wait(ctx), s.server.Close())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		err = errors.Join(err, s.listener.Close())
+	}
+	return err
+}
+
+// altSvcHandler advertises HTTP/3 with the Alt-Svc header, once it's being served
+func (w *WebServer) altSvcHandler(next http.Handler) http.Handler {
+	if w.serverHTTP3 == nil {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// fails until HTTP/3 is listening, with no port to advertise
+		_ = w.serverHTTP3.server.SetQuicHeaders(rw.Header())
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// validateHTTP3 ensures that HTTP/3 is served with HTTPS on a UDP port, with TLS 1.3
+func (w *WebServer) validateHTTP3() error {
+	if !w.HTTP3Enabled {
+		return nil
+	}
+	var errs []error
+	if !w.HTTPSPortEnabled {
+		errs = append(errs, errors.New("HTTP/3 requires HTTPS to be enabled"))
+	}
+	if _, _, err := net.SplitHostPort(w.HTTPSPort); err != nil || isListenerScheme(w.HTTPSPort) {
+		errs = append(errs, fmt.Errorf("HTTP/3 requires the HTTPS port to be a UDP address, not '%v'", w.HTTPSPort))
+	}
+	if v, err := parseTLSVersion(w.tlsPolicy().MaxVersion); err == nil && v != 0 && v < tlsVersions["1.3"] {
+		errs = append(errs, errors.New("HTTP/3 requires TLS 1.3, which the maximum TLS version excludes"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestWebServer_listen_http3(t *testing.T) {
+	dir := t.TempDir()
+	certificate := TLSCertificate{CertPath: path.Join(dir, "tls.crt"), KeyPath: path.Join(dir, "tls.key")}
+	writeTestCertificate(t, certificate.CertPath, certificate.KeyPath, "localhost")
+	serveFolder := path.Join(dir, "site")
+	if err := os.Mkdir(serveFolder, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(serveFolder, "index.html"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := New(
+		WithServeFolder(serveFolder),
+		WithAppPort("127.0.0.1:0"),
+		WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("127.0.0.1:0")}),
+		WithTLSCertificates("", false, certificate),
+		WithHTTP3(),
+		WithHSTS(time.Hour),
+	)
+	if errs := ws.Validate(); len(errs) > 0 {
+		t.Fatalf("invalid config: %v", errs)
+	}
+	ws.accessLogOut = io.Discard
+	ws.build()
+	servers, err := ws.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := map[string]string{}
+	for _, s := range servers {
+		s := s
+		addrs[s.name] = s.listener.Addr().String()
+		go func() { _ = s.server.Serve(s.listener) }()
+	}
+	t.Cleanup(func() {
+		for _, s := range servers {
+			_ = s.server.Shutdown(context.Background())
+		}
+	})
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}).Get("https://" + addrs["https"] + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	_, port, _ := net.SplitHostPort(addrs["http3"])
+	if got := resp.Header.Get("Alt-Svc"); !strings.Contains(got, `h3=":`+port+`"`) {
+		t.Errorf("Alt-Svc = %v, want h3 on port %v", got, port)
+	}
+
+	roundTripper := &http3.RoundTripper{TLSClientConfig: tlsConfig}
+	defer roundTripper.Close()
+	resp, err = (&http.Client{Transport: roundTripper}).Get("https://" + addrs["http3"] + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ProtoMajor != 3 {
+		t.Errorf("protocol = %v, want HTTP/3", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("GET over HTTP/3 = %v %q, want %v %q", resp.StatusCode, body, http.StatusOK, "hello")
+	}
+	if got, want := resp.Header.Get("Strict-Transport-Security"), "max-age="+strconv.Itoa(3600); got != want {
+		t.Errorf("Strict-Transport-Security = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/health"
+)
+
+// the path prefix of ACME HTTP-01 challenges
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// the minimum HSTS max age to be preloaded by browsers
+const hstsPreloadMinMaxAge = 365 * 24 * time.Hour
+
+// httpsRedirectHandler redirects requests to HTTPS, on the HTTPS port or at the HTTPS redirect origin.
+// ACME challenges are served by next, and the health endpoints are answered, without redirecting
+func (w *WebServer) httpsRedirectHandler(next http.Handler) http.Handler {
+	if !w.HTTPSRedirect {
+		return next
+	}
+	healthHandler := w.NewHealthFromWebServer().Handler()
+	_, port, _ := net.SplitHostPort(w.HTTPSPort)
+	origin := strings.TrimSuffix(w.HTTPSRedirectOrigin, "/")
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, acmeChallengePath):
+			next.ServeHTTP(rw, r)
+			return
+		case r.URL.Path == health.LivenessPath || r.URL.Path == health.ReadinessPath:
+			healthHandler.ServeHTTP(rw, r)
+			return
+		}
+		target := origin
+		if target == "" {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if host == "" {
+				http.Error(rw, "the request has no host to redirect to", http.StatusBadRequest)
+				return
+			}
+			u := url.URL{Scheme: "https", Host: net.JoinHostPort(host, port)}
+			if port == "443" {
+				u.Host = strings.TrimSuffix(u.Host, ":443")
+			}
+			target = u.String()
+		}
+		http.Redirect(rw, r, target+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// hstsHandler sets the Strict-Transport-Security header on responses over TLS, when a max age is set
+func (w *WebServer) hstsHandler(next http.Handler) http.Handler {
+	if w.HSTSMaxAge <= 0 {
+		return next
+	}
+	value := fmt.Sprintf("max-age=%d", int(w.HSTSMaxAge/time.Second))
+	if w.HSTSIncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if w.HSTSPreload {
+		value += "; preload"
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			rw.Header().Set("Strict-Transport-Security", value)
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// validateHTTPS ensures that requests can be redirected to HTTPS, and that the HSTS header is valid
+func (w *WebServer) validateHTTPS() error {
+	var errs []error
+	if w.HTTPSRedirectOrigin != "" {
+		u, err := url.Parse(w.HTTPSRedirectOrigin)
+		if err != nil || u.Scheme != "https" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("invalid HTTPS redirect origin '%v', expected https://host[:port]", w.HTTPSRedirectOrigin))
+		}
+	} else if w.HTTPSRedirect {
+		if !w.HTTPSPortEnabled {
+			errs = append(errs, errors.New("redirecting to HTTPS requires HTTPS to be enabled, or an HTTPS redirect origin"))
+		} else if isListenerScheme(w.HTTPSPort) {
+			errs = append(errs, fmt.Errorf("redirecting to HTTPS on '%v' requires an HTTPS redirect origin, as it isn't a TCP address", w.HTTPSPort))
+		}
+	}
+	if w.HSTSMaxAge < 0 {
+		errs = append(errs, errors.New("the HSTS max age must not be negative"))
+	}
+	if w.HSTSPreload && (w.HSTSMaxAge < hstsPreloadMinMaxAge || !w.HSTSIncludeSubDomains) {
+		errs = append(errs, fmt.Errorf("HSTS preload requires a max age of at least %v and including subdomains", hstsPreloadMinMaxAge))
+	}
+	return errors.Join(errs...)
+}
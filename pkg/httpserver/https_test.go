@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebServer_httpsRedirectHandler(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	tests := []struct {
+		name         string
+		opts         []Option
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "HTTPS port",
+			opts:         []Option{WithConfig(SourceOption, &Config{HTTPSPort: pointer(":8443")})},
+			target:       "http://example.com:8080/docs/?page=2",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://example.com:8443/docs/?page=2",
+		},
+		{
+			name:         "default HTTPS port",
+			opts:         []Option{WithConfig(SourceOption, &Config{HTTPSPort: pointer(":443")})},
+			target:       "http://example.com/docs/",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://example.com/docs/",
+		},
+		{
+			name:         "IPv6 host",
+			opts:         []Option{WithConfig(SourceOption, &Config{HTTPSPort: pointer(":443")})},
+			target:       "http://[2001:db8::1]:8080/",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://[2001:db8::1]/",
+		},
+		{
+			name:         "origin",
+			opts:         []Option{WithHTTPSRedirect("https://www.example.com/")},
+			target:       "http://example.com:8080/docs/?page=2",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://www.example.com/docs/?page=2",
+		},
+		{
+			name:       "ACME challenge",
+			target:     "http://example.com/.well-known/acme-challenge/token",
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:       "health",
+			target:     "http://example.com/healthz",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(append([]Option{WithServeFolder(t.TempDir()), WithHTTPSRedirect("")}, tt.opts...)...)
+			rec := httptest.NewRecorder()
+			w.httpsRedirectHandler(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("GET %v status = %v, want %v", tt.target, rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("GET %v location = %v, want %v", tt.target, got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestWebServer_hstsHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		tls  bool
+		want string
+	}{
+		{
+			name: "TLS",
+			opts: []Option{WithHSTS(24 * time.Hour)},
+			tls:  true,
+			want: "max-age=86400",
+		},
+		{
+			name: "preload",
+			opts: []Option{WithHSTS(2 * 365 * 24 * time.Hour), WithHSTSIncludeSubDomains(true), WithHSTSPreload(true)},
+			tls:  true,
+			want: "max-age=63072000; includeSubDomains; preload",
+		},
+		{
+			name: "plain HTTP",
+			opts: []Option{WithHSTS(24 * time.Hour)},
+		},
+		{
+			name: "unset",
+			tls:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(append([]Option{WithServeFolder(t.TempDir())}, tt.opts...)...)
+			w.accessLogOut = io.Discard
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			rec := httptest.NewRecorder()
+			w.hstsHandler(w).ServeHTTP(rec, req)
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,21 +3,34 @@ package httpserver
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/cors"
+	"golang.org/x/crypto/acme/autocert"
 
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/certs"
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/handlers"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/health"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/logging"
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/realip"
 )
 
 // ExtraHandler ...
@@ -30,201 +43,429 @@ type ExtraHandler struct {
 
 // WebServer configures the runtime
 type WebServer struct {
-	AppPort               string
-	HTTPAllowedOrigins    []string
-	Error404FilePath      string
-	ExtraHandlers         []*ExtraHandler
-	ExtraMiddleware       []func(http.Handler) http.Handler
-	GzipEnabled           bool
-	HTTPPort              string
-	HTTPSPort             string
-	HTTPSPortEnabled      bool
-	HeaderMap             map[string][]string
-	HeaderMapEnabled      bool
-	HeaderMapPath         string
-	HealthPort            string
-	HealthPortEnabled     bool
-	MetricsPort           string
-	MetricsPortEnabled    bool
-	RealIPHeader          string
-	RedirectRoutes        map[string]string
-	RedirectRoutesEnabled bool
-	RedirectRoutesPath    string
-	ServeFolder           string
-	TLSCertPath           string
-	TLSConfig             *tls.Config
-	TLSKeyPath            string
-	TemplateMap           map[string]string
-	TemplateMapEnabled    bool
-	TemplateMapPath       string
-	VueJSHistoryMode      bool
+	ACMECacheDir              string
+	ACMEDirectoryURL          string
+	ACMEEmail                 string
+	ACMEEnabled               bool
+	ACMEHostnames             []string
+	AccessLogAllowHeaders     []string
+	AccessLogFormat           string
+	AccessLogMaskQueryParams  []string
+	AccessLogRedactHeaders    []string
+	AccessLogTemplate         string
+	AppPort                   string
+	CORSAllowCredentials      bool
+	CORSAllowPrivateNetwork   bool
+	CORSAllowedHeaders        []string
+	CORSAllowedMethods        []string
+	CORSExposedHeaders        []string
+	CORSMaxAge                time.Duration
+	CORSRules                 []CORSRule
+	HTTPAllowedOrigins        []string
+	Error404FilePath          string
+	ExtraHandlers             []*ExtraHandler
+	ExtraMiddleware           []func(http.Handler) http.Handler
+	GzipEnabled               bool
+	H2CEnabled                bool
+	H2CMaxConcurrentStreams   int
+	H2CMaxStreamBuffer        int
+	HSTSIncludeSubDomains     bool
+	HSTSMaxAge                time.Duration
+	HSTSPreload               bool
+	HTTP3Enabled              bool
+	HTTPPort                  string
+	HTTPSPort                 string
+	HTTPSPortEnabled          bool
+	HTTPSRedirect             bool
+	HTTPSRedirectOrigin       string
+	HeaderMap                 map[string][]string
+	HeaderMapEnabled          bool
+	HeaderMapPath             string
+	HealthPort                string
+	HealthPortEnabled         bool
+	IdleTimeout               time.Duration
+	ListenerTimeouts          map[string]ServerTimeouts
+	LogFormat                 string
+	LogLevel                  string
+	MaxConnections            int
+	MaxConnectionsPerIP       int
+	MaxHeaderBytes            int
+	MetricsPort               string
+	MetricsPortEnabled        bool
+	ProxyProtocolEnabled      bool
+	ProxyProtocolTrustedCIDRs []string
+	ReadHeaderTimeout         time.Duration
+	ReadTimeout               time.Duration
+	RealIPHeader              string
+	RedirectRoutes            map[string]string
+	RedirectRoutesEnabled     bool
+	RedirectRoutesPath        string
+	ReloadInterval            time.Duration
+	ReloadOnSignal            bool
+	ServeFolder               string
+	ShutdownGraceTimeout      time.Duration
+	ShutdownPreStopDelay      time.Duration
+	TLSALPN                   []string
+	TLSCertDir                string
+	TLSCertPath               string
+	TLSCertificates           []TLSCertificate
+	TLSCipherSuites           []string
+	TLSClientAuth             string
+	TLSClientAuthRules        []TLSClientAuthRule
+	TLSClientCAPaths          []string
+	TLSConfig                 *tls.Config
+	TLSCurves                 []string
+	TLSKeyPath                string
+	TLSMaxVersion             string
+	TLSMinVersion             string
+	TLSPreset                 string
+	TLSRejectUnknownSNI       bool
+	TLSSessionTicketsDisabled bool
+	TemplateMap               map[string]string
+	TemplateMapEnabled        bool
+	TemplateMapPath           string
+	TrustedProxies            []string
+	UnixSocketGroup           string
+	UnixSocketMode            string
+	UnixSocketUser            string
+	UpgradeOnSignal           bool
+	VueJSHistoryMode          bool
+	WriteTimeout              time.Duration
 
+	accessLogOut  io.Writer
 	handler       *handlers.Handler
 	server        *http.Server
 	serverTLS     *http.Server
+	serverHTTP3   *http3Server
+	serverH2C     *h2cServer
+	metrics       *metrics.Metrics
+	health        *health.Health
+	configErr     error
+	configLayers  []configLayer
+	configLoaded  bool
+	configPath    string
+	configSources map[string]Source
 	dotfileLoaded bool
+	envConfigPath string
+	fileBase      *fileConfig
+	handlerSet    bool
+	loadedFiles   map[string]bool
+	tlsConfig     *tls.Config
+	draining      atomic.Bool
+	tlsErr        error
+	certStore     *certs.Store
+	acmeManager   *autocert.Manager
+
+	systemdListenersWithNames func() (map[string][]net.Listener, error)
+	systemdListeners          map[string][]net.Listener
+	systemdErr                error
+	systemdOnce               sync.Once
+
+	inheritedListeners map[string]net.Listener
+	inheritErr         error
+	inheritOnce        sync.Once
+	upgradeArgs        []string
+	upgraded           atomic.Bool
+
+	httpHandler  atomic.Pointer[http.Handler]
+	handlerMu    sync.Mutex
+	mu           sync.Mutex
+	servers      []*boundServer
+	shutdownOnce *shutdownOnce
 }
 
-// NewWebServer returns a default WebServer, as per environment configuration
-//
-// TODO clean up function
-func NewWebServer() *WebServer {
-	httpOrigins, err := common.GetHTTPAllowedOrigins()
-	if err != nil {
-		log.Printf("error: failed to load allowed http origins: %v\n", err)
-	}
+// shutdownOnce shuts down the servers of a Start once, returning the same error to every caller
+type shutdownOnce struct {
+	once sync.Once
+	err  error
+}
+
+// New returns a WebServer configured only by the options, without reading the environment.
+// Routing is assembled when the WebServer is started
+func New(opts ...Option) *WebServer {
 	w := &WebServer{
-		AppPort:               common.GetAppPort(),
-		Error404FilePath:      common.Get404PageFileName(),
-		GzipEnabled:           common.GetEnableGZIP(),
-		HTTPPort:              common.GetAppPort(),
-		HTTPSPort:             common.GetAppHTTPSPort(),
-		HTTPSPortEnabled:      common.GetAppEnableHTTPS(),
-		HTTPAllowedOrigins:    httpOrigins,
-		HeaderMapEnabled:      common.GetHeaderSetEnable(),
-		HeaderMapPath:         common.GetHeaderMapPath(),
-		HealthPort:            common.GetAppHealthPort(),
-		HealthPortEnabled:     common.GetAppHealthPortEnabled(),
-		MetricsPort:           common.GetAppMetricsPort(),
-		MetricsPortEnabled:    common.GetAppMetricsEnabled(),
-		RealIPHeader:          common.GetAppRealIPHeader(),
-		RedirectRoutesEnabled: common.GetRedirectRoutesEnabled(),
-		RedirectRoutesPath:    common.GetRedirectRoutesPath(),
-		ServeFolder:           common.GetServeFolder(),
-		TLSCertPath:           common.GetAppHTTPSCrtPath(),
-		TLSKeyPath:            common.GetAppHTTPSKeyPath(),
+		ACMECacheDir:          common.DefaultACMECacheDir,
+		ACMEDirectoryURL:      common.DefaultACMEDirectoryURL,
+		AccessLogFormat:       common.DefaultAccessLogFormat,
+		AppPort:               common.DefaultAppPort,
+		CORSAllowedHeaders:    []string{"*"},
+		CORSAllowedMethods:    []string{http.MethodGet},
+		Error404FilePath:      common.Default404PageFileName,
+		GzipEnabled:           true,
+		HTTPAllowedOrigins:    []string{"*"},
+		HTTPPort:              common.DefaultAppPort,
+		HTTPSPort:             common.DefaultHTTPSPort,
+		HealthPort:            common.DefaultHealthPort,
+		IdleTimeout:           common.DefaultIdleTimeout,
+		LogFormat:             common.DefaultLogFormat,
+		LogLevel:              common.DefaultLogLevel,
+		MaxHeaderBytes:        common.DefaultMaxHeaderBytes,
+		MetricsPort:           common.DefaultMetricsPort,
+		ReadHeaderTimeout:     common.DefaultReadHeaderTimeout,
+		ReadTimeout:           common.DefaultReadTimeout,
+		RedirectRoutesEnabled: true,
+		ServeFolder:           ".",
+		ShutdownGraceTimeout:  common.DefaultShutdownGraceTimeout,
 		TemplateMapEnabled:    true,
-		TemplateMapPath:       common.GetTemplateMapPath(),
-		VueJSHistoryMode:      common.GetVuejsHistoryMode(),
+		WriteTimeout:          common.DefaultWriteTimeout,
+		accessLogOut:          os.Stderr,
 		handler:               &handlers.Handler{},
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.resolveConfig()
+	return w
+}
+
+// NewWebServer returns a default WebServer, as per environment configuration.
+// Unlike New, configuration files are loaded and routing is assembled immediately
+func NewWebServer() *WebServer {
+	w := New(FromEnv())
+	w.build()
+	return w
+}
+
+// loadConfig loads the dotfile, redirect routes, header map and template map, once
+func (w *WebServer) loadConfig() {
+	if w.configLoaded {
+		return
+	}
+	w.configLoaded = true
+	if w.handler == nil {
+		w.handler = &handlers.Handler{}
+	}
+	if w.fileBase == nil {
+		base := w.snapshotFileConfig()
+		w.fileBase = &base
+	}
 	cfg, err := common.LoadDotfileConfig(w.ServeFolder)
 	if err != nil {
-		log.Printf("error loading dotfile config: %v\n", err)
+		slog.Error("failed to load dotfile config", "error", err)
 	} else if cfg != nil {
 		w.dotfileLoaded = true
-		w.VueJSHistoryMode = cfg.HistoryMode
-		if cfg.RedirectRoutes != nil {
-			w.RedirectRoutes = cfg.RedirectRoutes
-		}
-		if cfg.HeaderMap != nil {
-			w.HeaderMap = cfg.HeaderMap
+		w.applyConfig(SourceDotfile, ConfigFromDotfile(cfg))
+	}
+	if w.RedirectRoutesEnabled && w.RedirectRoutes == nil && w.RedirectRoutesPath != "" {
+		redirectRoutes, err := common.LoadRedirectRoutesConfig(w.RedirectRoutesPath)
+		if err != nil {
+			slog.Warn("failed to load redirect routes", "path", w.RedirectRoutesPath, "error", err)
 		}
-		if cfg.TemplateMap != nil {
-			w.TemplateMap = cfg.TemplateMap
+		w.RedirectRoutes = redirectRoutes
+		if err == nil && redirectRoutes != nil {
+			w.markFileLoaded(w.RedirectRoutesPath)
 		}
-		if w.HeaderMap != nil {
-			w.HeaderMapEnabled = true
+	}
+	if w.HeaderMap != nil || w.HeaderMapPath != "" {
+		if _, err := w.LoadHeaderMap(); err != nil {
+			slog.Log(context.Background(), errorLevelIf(w.HeaderMapEnabled), "failed to load header map", "error", err)
 		}
-		w.Error404FilePath = cfg.Error404FilePath
-		if w.Error404FilePath == "" {
-			w.Error404FilePath = common.Get404PageFileName()
+	}
+	if w.TemplateMap != nil || w.TemplateMapPath != "" {
+		if _, err := w.LoadTemplateMap(); err != nil {
+			slog.Log(context.Background(), errorLevelIf(w.VueJSHistoryMode && w.TemplateMapEnabled), "failed to load template map", "error", err)
 		}
 	}
+}
+
+// hasPathPrefix returns if the path is the prefix, or is under it by whole segments,
+// such that /admin matches /admin and /admin/users, but not /administrator
+func hasPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// errorLevelIf returns the error level when in use, otherwise the debug level
+func errorLevelIf(inUse bool) slog.Level {
+	if inUse {
+		return slog.LevelError
+	}
+	return slog.LevelDebug
+}
+
+// Handler returns the fully assembled handler, with redirects, extra handlers, CORS,
+// logging and file serving. It is assembled on first use, or after a setter is called,
+// and may be mounted in another server or driven by httptest
+func (w *WebServer) Handler() http.Handler {
+	if h := w.httpHandler.Load(); h != nil {
+		return *h
+	}
+	w.handlerMu.Lock()
+	defer w.handlerMu.Unlock()
+	if h := w.httpHandler.Load(); h != nil {
+		return *h
+	}
+	w.loadConfig()
+	h := w.assembleHandler()
+	w.httpHandler.Store(&h)
+	return h
+}
+
+// ServeHTTP serves a request with the assembled handler
+func (w *WebServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.Handler().ServeHTTP(rw, r)
+}
+
+// resetHandler discards the assembled handler, for it to be assembled again with changes
+func (w *WebServer) resetHandler() {
+	w.handlerMu.Lock()
+	defer w.handlerMu.Unlock()
+	w.httpHandler.Store(nil)
+}
+
+// assembleHandler assembles the routing from the current configuration
+func (w *WebServer) assembleHandler() http.Handler {
 	router := mux.NewRouter().StrictSlash(false)
-	router.Use(common.Logging)
-	// NOTE is this ever called?
+	router.Use(w.clientCertMiddleware)
 	for _, m := range w.ExtraMiddleware {
 		router.Use(m)
 	}
 	if w.RedirectRoutesEnabled {
-		if w.RedirectRoutes == nil {
-			redirectRoutes, err := common.LoadRedirectRoutesConfig(w.RedirectRoutesPath)
-			if err != nil {
-				log.Println("Warning: failed to load redirect routes")
-			}
-			w.RedirectRoutes = redirectRoutes
-		}
 		for from, to := range w.RedirectRoutes {
 			router.HandleFunc(from, w.handler.ServeStandardRedirect(from, to)).Methods(http.MethodGet)
 		}
 	}
-
-	if _, err := w.LoadHeaderMap(); err != nil {
-		log.Printf("error: failed to load header map: %v\n", err)
-	}
-	if _, err := w.LoadTemplateMap(); err != nil {
-		log.Printf("error: failed to load template map: %v\n", err)
-	}
-
 	for _, h := range w.ExtraHandlers {
 		if h.Path == "/" {
-			log.Println("Warning: path / not allowed for extra handlers")
+			slog.Warn("path / not allowed for extra handlers")
 			continue
 		}
-		router.HandleFunc(h.Path, h.HandlerFunc).Methods(h.HTTPMethods...)
+		router.Handle(h.Path, metrics.ServingModeHandler(metrics.ServingModeExtraHandler, h.HandlerFunc)).Methods(h.HTTPMethods...)
+	}
+	if !w.handlerSet {
+		w.handler = w.newHandlerForWebServer()
 	}
-	w.handler = w.newHandlerForWebServer()
 
 	fullServePath, _ := filepath.Abs(w.ServeFolder)
-	log.Printf("Serving folder '%v'\n", fullServePath)
+	slog.Info("serving folder", "path", fullServePath)
 	router.PathPrefix("/").Handler(w.handler.ServeHandler())
 
-	c := cors.New(cors.Options{
-		AllowedOrigins:   w.HTTPAllowedOrigins,
-		AllowedHeaders:   []string{"*"},
-		AllowedMethods:   []string{"GET"},
-		AllowCredentials: true,
-	})
+	// requests answered before the router, such as CORS preflights and 405s, are also logged and counted
+	return w.newRealIPResolver().Middleware(w.newAccessLogger().Middleware(metrics.Middleware(w.corsHandler(router))))
+}
 
-	// Serve regular HTTP
-	w.server = &http.Server{
-		Handler:      c.Handler(router),
-		Addr:         w.AppPort,
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+// newRealIPResolver returns the resolver of client IPs, trusting no headers when the trusted proxies are invalid
+func (w *WebServer) newRealIPResolver() *realip.Resolver {
+	resolver, err := realip.NewResolver(w.RealIPHeader, w.TrustedProxies)
+	if err != nil {
+		slog.Error("failed to parse trusted proxies, using the remote address for the client IP", "error", err)
+		return &realip.Resolver{}
 	}
-	if w.HTTPSPortEnabled {
+	return resolver
+}
+
+// newAccessLogger returns the access logger, falling back to JSON when the format is invalid
+func (w *WebServer) newAccessLogger() *logging.AccessLogger {
+	out := w.accessLogOut
+	if out == nil {
+		out = os.Stderr
+	}
+	accessLogger, err := logging.NewAccessLogger(out, w.AccessLogFormat, w.AccessLogTemplate, realip.FromRequest)
+	if err != nil {
+		slog.Error("failed to create access logger, logging as JSON", "error", err)
+		accessLogger, _ = logging.NewAccessLogger(out, logging.AccessFormatJSON, "", realip.FromRequest)
+	}
+	accessLogger.Redactor = logging.NewRedactor(w.AccessLogRedactHeaders, w.AccessLogAllowHeaders, w.AccessLogMaskQueryParams)
+	return accessLogger
+}
+
+// build assembles the servers from the current configuration.
+// The servers serve with the WebServer, for reloaded routing to be swapped in
+func (w *WebServer) build() {
+	w.Handler()
+	if w.RealIPHeader != "" && len(w.TrustedProxies) == 0 {
+		slog.Warn("the real IP header is ignored without trusted proxies, set trusted proxies to read it", "header", w.RealIPHeader)
+	}
+	if w.HTTPSPortEnabled && w.TLSConfig == nil {
 		if _, err := w.LoadTLS(); err != nil {
-			log.Printf("error: failed to load TLS: %v\n", err)
+			slog.Error("failed to load TLS", "error", err)
 		}
+	}
+	// Serve regular HTTP, answering ACME HTTP-01 challenges
+	w.server = &http.Server{
+		Handler: w.acmeHandler(w.httpsRedirectHandler(w)),
+		Addr:    w.AppPort,
+	}
+	w.configureServer("http")(w.server)
+	w.serverH2C = nil
+	if w.H2CEnabled {
+		w.serverH2C = w.configureH2C(w.server)
+	}
+	w.serverHTTP3 = nil
+	if w.HTTPSPortEnabled && w.HTTP3Enabled {
+		w.serverHTTP3 = w.newHTTP3Server(w.hstsHandler(w))
+	}
+	if w.HTTPSPortEnabled {
 		w.serverTLS = &http.Server{
-			Handler:      c.Handler(router),
-			Addr:         w.HTTPSPort,
-			WriteTimeout: 15 * time.Second,
-			ReadTimeout:  15 * time.Second,
-			TLSConfig:    w.TLSConfig,
+			Handler:   w.altSvcHandler(w.hstsHandler(w)),
+			Addr:      w.HTTPSPort,
+			TLSConfig: w.TLSConfig,
 		}
+		w.configureServer("https")(w.serverTLS)
 	}
-
-	return w
 }
 
 // SetServeFolder sets the path to the ServeFolder
 func (w *WebServer) SetServeFolder(path string) *WebServer {
 	w.ServeFolder = path
+	w.resetHandler()
 	return w
 }
 
 // SetExtraHandlers sets extra http handlers
 func (w *WebServer) SetExtraHandlers(hs ...*ExtraHandler) *WebServer {
 	w.ExtraHandlers = hs
+	w.resetHandler()
 	return w
 }
 
 // SetExtraMiddleware sets extra http middleware
 func (w *WebServer) SetExtraMiddleware(m ...func(http.Handler) http.Handler) *WebServer {
 	w.ExtraMiddleware = m
+	w.resetHandler()
 	return w
 }
 
-// LoadTLS loads in the TLS certs
+// LoadTLS loads in the TLS certs, which are served with GetCertificate and reloaded when the files change.
+// The certificate at TLSCertPath is served by default, followed by TLSCertificates and those in TLSCertDir.
+// With ACME, certificates are obtained for the ACME hostnames, and the files serve the other names.
+// A config given with WithTLSConfig is served in their place.
+// The TLS policy and client certificate authentication are applied, and handshakes are counted in the metrics
 func (w *WebServer) LoadTLS() (*WebServer, error) {
-	w.TLSConfig = &tls.Config{}
-	w.TLSConfig.Certificates = make([]tls.Certificate, 1)
-	loadedCert, err := tls.LoadX509KeyPair(w.TLSCertPath, w.TLSKeyPath)
-	if err != nil {
-		return w, err
+	var err error
+	switch {
+	case w.tlsConfig != nil:
+		w.TLSConfig = w.tlsConfig.Clone()
+	case w.ACMEEnabled:
+		err = w.loadACME()
+	default:
+		var store *certs.Store
+		store, err = w.newCertStore()
+		w.certStore = store
+		w.TLSConfig = &tls.Config{GetCertificate: store.GetCertificate}
 	}
-	w.TLSConfig.Certificates[0] = loadedCert
-	return w, nil
+	err = errors.Join(err, w.tlsPolicy().apply(w.TLSConfig), w.configureClientAuth(w.TLSConfig))
+	recordHandshakes(w.TLSConfig)
+	w.tlsErr = err
+	return w, err
+}
+
+// newCertStore returns the store of the certificate files
+func (w *WebServer) newCertStore() (*certs.Store, error) {
+	var pairs []certs.Pair
+	if w.TLSCertPath != "" || (len(w.TLSCertificates) == 0 && w.TLSCertDir == "") {
+		pairs = append(pairs, certs.Pair{CertPath: w.TLSCertPath, KeyPath: w.TLSKeyPath})
+	}
+	for _, c := range w.TLSCertificates {
+		pairs = append(pairs, certs.Pair{CertPath: c.CertPath, KeyPath: c.KeyPath})
+	}
+	return certs.NewStore(pairs, w.TLSCertDir, w.TLSRejectUnknownSNI)
 }
 
 // LoadTemplateMap loads the template map from the path
 func (w *WebServer) LoadTemplateMap() (*WebServer, error) {
 	if w.TemplateMap == nil && !w.dotfileLoaded {
 		if _, err := os.Stat(w.TemplateMapPath); os.IsNotExist(err) {
-			log.Printf("[notice] history mode templating is enabled, template maps (currently set to '%v') can also be used\n", w.TemplateMapPath)
+			slog.Debug("history mode templating is enabled, template maps can also be used", "path", w.TemplateMapPath)
 			return w, fmt.Errorf("error: template map file not found")
 		}
 		configMap, err := common.LoadTemplateMapConfig(w.TemplateMapPath)
@@ -232,6 +473,7 @@ func (w *WebServer) LoadTemplateMap() (*WebServer, error) {
 			return w, err
 		}
 		w.TemplateMap = configMap
+		w.markFileLoaded(w.TemplateMapPath)
 	}
 	// TODO tidy condition of eval from env
 	w.TemplateMap = common.EvaluateEnvFromMap(w.TemplateMap, !w.dotfileLoaded)
@@ -241,7 +483,9 @@ func (w *WebServer) LoadTemplateMap() (*WebServer, error) {
 
 // SetTemplateMap set the template map
 func (w *WebServer) SetTemplateMap(input map[string]string) *WebServer {
-	w.handler.TemplateMap = common.EvaluateEnvFromMap(input, !w.dotfileLoaded)
+	w.TemplateMap = common.EvaluateEnvFromMap(input, !w.dotfileLoaded)
+	w.handler.TemplateMap = w.TemplateMap
+	w.resetHandler()
 	return w
 }
 
@@ -249,7 +493,7 @@ func (w *WebServer) SetTemplateMap(input map[string]string) *WebServer {
 func (w *WebServer) LoadHeaderMap() (*WebServer, error) {
 	if w.HeaderMap == nil && !w.dotfileLoaded {
 		if _, err := os.Stat(w.HeaderMapPath); os.IsNotExist(err) {
-			log.Printf("[notice] header templating is enabled, header template maps (currently set to '%v') can also be used\n", w.HeaderMapPath)
+			slog.Debug("header templating is enabled, header template maps can also be used", "path", w.HeaderMapPath)
 			return w, fmt.Errorf("error: header template not found")
 		}
 		headerMap, err := common.LoadHeaderMapConfig(w.HeaderMapPath)
@@ -257,6 +501,7 @@ func (w *WebServer) LoadHeaderMap() (*WebServer, error) {
 			return w, err
 		}
 		w.HeaderMap = headerMap
+		w.markFileLoaded(w.HeaderMapPath)
 	}
 	// TODO tidy condition of eval from env
 	w.HeaderMap = common.EvaluateEnvFromHeaderMap(w.HeaderMap, !w.dotfileLoaded)
@@ -266,7 +511,9 @@ func (w *WebServer) LoadHeaderMap() (*WebServer, error) {
 
 // SetHeaderMap sets the header map
 func (w *WebServer) SetHeaderMap(input map[string][]string) *WebServer {
-	w.handler.HeaderMap = common.EvaluateEnvFromHeaderMap(input, !w.dotfileLoaded)
+	w.HeaderMap = common.EvaluateEnvFromHeaderMap(input, !w.dotfileLoaded)
+	w.handler.HeaderMap = w.HeaderMap
+	w.resetHandler()
 	return w
 }
 
@@ -283,72 +530,344 @@ func (w *WebServer) newHandlerForWebServer() *handlers.Handler {
 	}
 }
 
-// SetHandler sets a new handler
+// SetHandler sets a new handler, used in place of one assembled from the configuration
 func (w *WebServer) SetHandler(input *handlers.Handler) *WebServer {
 	w.handler = input
+	w.handlerSet = true
+	w.resetHandler()
 	return w
 }
 
 // NewMetricsFromWebServer returns a new metrics from a webserver
 func (w *WebServer) NewMetricsFromWebServer() *metrics.Metrics {
 	return &metrics.Metrics{
-		Enabled: w.MetricsPortEnabled,
-		Port:    w.MetricsPort,
+		Enabled:         w.MetricsPortEnabled,
+		Port:            w.MetricsPort,
+		ShutdownTimeout: w.ShutdownGraceTimeout,
+		ConfigureServer: w.configureServer("metrics"),
 	}
 }
 
-// Listen starting listening according to the configuration
-func (w *WebServer) Listen(ch ...<-chan bool) {
-	go w.NewMetricsFromWebServer().Handle()
+// NewHealthFromWebServer returns a new health from a webserver
+func (w *WebServer) NewHealthFromWebServer() *health.Health {
+	return &health.Health{
+		Enabled:         w.HealthPortEnabled,
+		Port:            w.HealthPort,
+		ShutdownTimeout: w.ShutdownGraceTimeout,
+		ConfigureServer: w.configureServer("health"),
+		ReadinessChecks: []health.Check{
+			{Name: "serve-folder", Check: w.checkServeFolder},
+			{Name: "index-template", Check: w.checkIndexTemplate},
+			{Name: "tls", Check: w.checkTLS},
+			{Name: "draining", Check: w.checkNotDraining},
+		},
+	}
+}
+
+// checkServeFolder ensures that the serve folder exists and is readable
+func (w *WebServer) checkServeFolder() error {
+	f, err := os.Open(w.ServeFolder)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("serve folder '%v' is not a directory", w.ServeFolder)
+	}
+	if _, err := f.Readdirnames(1); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// checkIndexTemplate ensures that the index.html parses as a template, when in history mode
+func (w *WebServer) checkIndexTemplate() error {
+	w.handlerMu.Lock()
+	defer w.handlerMu.Unlock()
+	return w.indexTemplateErr()
+}
+
+// indexTemplateErr returns the error parsing index.html as a template, when in history mode
+func (w *WebServer) indexTemplateErr() error {
+	if !w.VueJSHistoryMode {
+		return nil
+	}
+	_, err := template.ParseFiles(path.Join(w.ServeFolder, "index.html"))
+	return err
+}
+
+// checkTLS ensures that the TLS certificate loaded, when HTTPS is enabled
+func (w *WebServer) checkTLS() error {
+	if !w.HTTPSPortEnabled {
+		return nil
+	}
+	if w.certStore != nil {
+		return w.certStore.Err()
+	}
+	return w.tlsErr
+}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+// checkNotDraining fails readiness once shutting down has begun
+func (w *WebServer) checkNotDraining() error {
+	if w.draining.Load() {
+		return fmt.Errorf("shutting down")
+	}
+	return nil
+}
 
-	go func() {
-		if len(ch) == 0 {
-			return
+// server is served on a listener and gracefully shut down
+type server interface {
+	Serve(net.Listener) error
+	Shutdown(context.Context) error
+}
+
+// boundServer is a server bound to its listener
+type boundServer struct {
+	name     string
+	server   server
+	listener net.Listener
+	// raw is the listener before wrapping, to pass to an upgraded process
+	raw net.Listener
+}
+
+// listen binds every enabled server to its port, or to the listener passed by the process which upgraded to this one
+func (w *WebServer) listen() (servers []*boundServer, err error) {
+	type candidate struct {
+		name          string
+		enabled       bool
+		addr          string
+		server        server
+		tls           bool
+		proxyProtocol bool
+		limit         bool
+		packet        bool
+	}
+	var httpServer server = w.server
+	if w.serverH2C != nil {
+		httpServer = w.serverH2C
+	}
+	candidates := []candidate{
+		{name: "http", enabled: true, addr: w.AppPort, server: httpServer, proxyProtocol: w.ProxyProtocolEnabled, limit: true},
+		{name: "https", enabled: w.HTTPSPortEnabled, addr: w.HTTPSPort, server: w.serverTLS, tls: true, proxyProtocol: w.ProxyProtocolEnabled, limit: true},
+		{name: "http3", enabled: w.serverHTTP3 != nil, addr: w.HTTPSPort, server: w.serverHTTP3, packet: true},
+		{name: "metrics", enabled: w.MetricsPortEnabled, addr: w.MetricsPort, server: w.metrics},
+		{name: "health", enabled: w.HealthPortEnabled, addr: w.HealthPort, server: w.health},
+	}
+	// the limits are shared between the HTTP and HTTPS listeners
+	var limiter *connLimiter
+	for _, c := range candidates {
+		if !c.enabled {
+			continue
 		}
-		for {
-			c, ok := <-ch[0]
-			log.Println("<- receieved event:", c, ok)
-			if !ok {
-				break
+		l, err := w.inheritedListener(c.name)
+		if err == nil && l == nil {
+			if c.packet {
+				l, err = listenPacket(c.addr)
+			} else {
+				l, err = w.listenAddr(c.addr)
 			}
-			if c {
-				done <- os.Interrupt
+		}
+		if err != nil {
+			for _, s := range servers {
+				s.listener.Close()
 			}
+			return nil, fmt.Errorf("failed to listen for %v on %v: %w", c.name, c.addr, err)
 		}
-	}()
-	go func() {
-		log.Println("Listening on", w.AppPort)
-		if err := w.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal(err)
+		raw := l
+		if c.proxyProtocol {
+			// the PROXY protocol header is sent before the TLS handshake
+			pl, err := w.proxyProtocolListener(l)
+			if err != nil {
+				l.Close()
+				for _, s := range servers {
+					s.listener.Close()
+				}
+				return nil, fmt.Errorf("failed to listen for %v with the PROXY protocol: %w", c.name, err)
+			}
+			l = pl
+		}
+		if c.limit && (w.MaxConnections > 0 || w.MaxConnectionsPerIP > 0) {
+			if limiter == nil {
+				limiter = newConnLimiter(w.MaxConnections, w.MaxConnectionsPerIP)
+			}
+			l = &limitListener{Listener: l, name: c.name, limiter: limiter}
+		}
+		if c.tls {
+			l = tls.NewListener(l, w.TLSConfig)
 		}
-	}()
+		slog.Info("listening", "server", c.name, "addr", l.Addr().String())
+		servers = append(servers, &boundServer{name: c.name, server: c.server, listener: l, raw: raw})
+	}
+	// listeners inherited for servers which are no longer enabled
+	for name, l := range w.inheritedListeners {
+		l.Close()
+		delete(w.inheritedListeners, name)
+	}
+	return servers, nil
+}
 
-	// Optionally, serve HTTPS
-	if w.HTTPSPortEnabled {
-		listener, err := tls.Listen("tcp", w.HTTPSPort, w.TLSConfig)
+// Start serves according to the configuration until the context is cancelled,
+// returning an error if any server fails to bind or serve. Signals are left to the caller,
+// unless enabled with WithReloadOnSignal or WithUpgradeOnSignal
+func (w *WebServer) Start(ctx context.Context) error {
+	if w.configErr != nil {
+		return w.configErr
+	}
+	w.mu.Lock()
+	w.build()
+	w.logConfigSources(slog.Default())
+	w.metrics = w.NewMetricsFromWebServer()
+	w.health = w.NewHealthFromWebServer()
+	servers, err := w.listen()
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.servers = servers
+	w.shutdownOnce = &shutdownOnce{}
+	w.draining.Store(false)
+	w.upgraded.Store(false)
+	w.mu.Unlock()
+
+	reloadCtx, stopReload := context.WithCancel(ctx)
+	defer stopReload()
+	if w.ReloadOnSignal {
+		go w.reloadOnSignal(reloadCtx)
+	}
+	go w.watchConfig(reloadCtx, w.hashConfigFiles())
+	if w.certStore != nil {
+		go w.certStore.Watch(reloadCtx, w.ReloadInterval)
+	}
+	upgraded := make(chan *os.Process, 1)
+	if w.UpgradeOnSignal {
+		go w.upgradeOnSignal(reloadCtx, upgraded)
+	}
+
+	errs := make(chan error, len(servers))
+	for _, s := range servers {
+		go func(s *boundServer) {
+			err := s.server.Serve(s.listener)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("failed to serve %v: %w", s.name, err)
+				return
+			}
+			errs <- nil
+		}(s)
+	}
+
+	notifyUpgradeReady()
+
+	select {
+	case <-ctx.Done():
+		return w.Shutdown(context.Background())
+	case p := <-upgraded:
+		slog.Info("shutting down, upgraded", "pid", p.Pid)
+		return w.Shutdown(context.Background())
+	case err := <-errs:
+		// a server has stopped, either from failing or from Shutdown
+		shutdownErr := w.Shutdown(context.Background())
 		if err != nil {
-			log.Panicf("[fatal] Error creating TLS listener: %v\n", err)
+			return err
 		}
+		return shutdownErr
+	}
+}
+
+// Shutdown drains and then gracefully stops every server, waiting for the
+// pre-stop delay and then up to the grace timeout for in-flight requests.
+// Each Start is shut down once, after which the WebServer may be started again
+func (w *WebServer) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	s := w.shutdownOnce
+	w.mu.Unlock()
+	if s == nil {
+		// not started
+		return nil
+	}
+	s.once.Do(func() {
+		s.err = w.shutdown(ctx)
+	})
+	return s.err
+}
+
+func (w *WebServer) shutdown(ctx context.Context) error {
+	w.drain(ctx)
+	ctx, cancel := context.WithTimeout(ctx, w.shutdownGraceTimeout())
+	defer cancel()
+	w.setShutdownPhase(metrics.ShutdownPhaseShutdown)
+
+	w.mu.Lock()
+	servers := w.servers
+	w.mu.Unlock()
+	var errs []error
+	// metrics and health are last, to be available for as long as possible
+	for _, s := range servers {
+		if err := s.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%v server didn't exit gracefully: %w", s.name, err))
+		}
+	}
+	w.setShutdownPhase(metrics.ShutdownPhaseStopped)
+	return errors.Join(errs...)
+}
+
+// Listen starts listening according to the configuration, until interrupted
+//
+// Deprecated: use Start, which returns errors and leaves handling signals to the caller
+func (w *WebServer) Listen(ch ...<-chan bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if len(ch) > 0 {
 		go func() {
-			log.Println("Listening on", w.HTTPSPort)
-			log.Println(w.serverTLS.Serve(listener))
+			for {
+				c, ok := <-ch[0]
+				slog.Debug("received event", "event", c, "ok", ok)
+				if !ok {
+					break
+				}
+				if c {
+					stop()
+				}
+			}
 		}()
 	}
+	if err := w.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	<-done
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := w.server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server didn't exit gracefully %v", err)
+// drain fails readiness and keeps serving for the pre-stop delay,
+// giving load balancers time to stop sending new requests
+func (w *WebServer) drain(ctx context.Context) {
+	if w.upgraded.Load() {
+		// the upgraded process serves new connections on the same listeners
+		return
 	}
-	if w.HTTPSPortEnabled {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := w.serverTLS.Shutdown(ctx); err != nil {
-			log.Fatalf("Server didn't exit gracefully %v", err)
-		}
+	w.draining.Store(true)
+	w.setShutdownPhase(metrics.ShutdownPhaseDraining)
+	if w.ShutdownPreStopDelay <= 0 {
+		return
+	}
+	slog.Info("waiting before shutting down", "delay", w.ShutdownPreStopDelay)
+	select {
+	case <-time.After(w.ShutdownPreStopDelay):
+	case <-ctx.Done():
+	}
+}
+
+// setShutdownPhase logs and records the current phase of shutting down
+func (w *WebServer) setShutdownPhase(phase string) {
+	slog.Info("shutdown phase", "phase", phase)
+	metrics.SetShutdownPhase(phase)
+}
+
+// shutdownGraceTimeout returns the time given to servers to finish in-flight requests
+func (w *WebServer) shutdownGraceTimeout() time.Duration {
+	if w.ShutdownGraceTimeout <= 0 {
+		return common.DefaultShutdownGraceTimeout
 	}
+	return w.ShutdownGraceTimeout
 }
@@ -1,19 +1,24 @@
 package httpserver
 
 import (
+	"context"
 	"crypto/tls"
 	_ "embed"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/handlers"
 	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/realip"
 )
 
 var (
@@ -584,7 +589,7 @@ func TestWebServer_LoadTLS(t *testing.T) {
 		{
 			name:         "no keys",
 			errorMessage: "no such file or directory",
-			want:         1,
+			want:         0,
 		},
 	}
 	for _, tt := range tests {
@@ -647,8 +652,12 @@ func TestWebServer_LoadTLS(t *testing.T) {
 			} else if err != nil && !strings.Contains(err.Error(), tt.errorMessage) {
 				t.Fatalf("unexpected error loading tls cert: %v", err)
 			}
-			if !reflect.DeepEqual(len(got.TLSConfig.Certificates), tt.want) {
-				t.Errorf("WebServer.LoadTLS() = %v, want %v", len(got.TLSConfig.Certificates), tt.want)
+			served := 0
+			if cert, _ := got.TLSConfig.GetCertificate(&tls.ClientHelloInfo{}); cert != nil {
+				served = 1
+			}
+			if served != tt.want {
+				t.Errorf("WebServer.LoadTLS() served certificates = %v, want %v", served, tt.want)
 			}
 		})
 	}
@@ -1581,14 +1590,108 @@ func TestWebServer_NewMetricsFromWebServer(t *testing.T) {
 				server:                tt.fields.server,
 				serverTLS:             tt.fields.serverTLS,
 			}
-			if got := w.NewMetricsFromWebServer(); !reflect.DeepEqual(*got, *tt.want) {
+			got := w.NewMetricsFromWebServer()
+			if got.ConfigureServer == nil {
+				t.Errorf("WebServer.NewMetricsFromWebServer().ConfigureServer = nil, want the metrics server timeouts")
+			}
+			got.ConfigureServer = nil
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("WebServer.NewMetricsFromWebServer() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestWebServer_Listen(t *testing.T) {
+func TestWebServer_NewHealthFromWebServer(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            map[string]string
+		noServeFolder    bool
+		vueJSHistoryMode bool
+		httpsPortEnabled bool
+		tlsErr           error
+		draining         bool
+		want             map[string]bool
+	}{
+		{
+			name:  "basic",
+			files: map[string]string{"index.html": "<h1>hello</h1>"},
+			want:  map[string]bool{"serve-folder": true, "index-template": true, "tls": true, "draining": true},
+		},
+		{
+			name:          "missing serve folder",
+			noServeFolder: true,
+			want:          map[string]bool{"serve-folder": false, "index-template": true, "tls": true, "draining": true},
+		},
+		{
+			name:             "history mode with a bad template",
+			files:            map[string]string{"index.html": "<h1>{{ .Bad </h1>"},
+			vueJSHistoryMode: true,
+			want:             map[string]bool{"serve-folder": true, "index-template": false, "tls": true, "draining": true},
+		},
+		{
+			name:             "history mode without an index",
+			vueJSHistoryMode: true,
+			want:             map[string]bool{"serve-folder": true, "index-template": false, "tls": true, "draining": true},
+		},
+		{
+			name:             "tls failed to load",
+			httpsPortEnabled: true,
+			tlsErr:           fmt.Errorf("failed to load"),
+			want:             map[string]bool{"serve-folder": true, "index-template": true, "tls": false, "draining": true},
+		},
+		{
+			name:   "tls error ignored when https is disabled",
+			tlsErr: fmt.Errorf("failed to load"),
+			want:   map[string]bool{"serve-folder": true, "index-template": true, "tls": true, "draining": true},
+		},
+		{
+			name:     "draining",
+			draining: true,
+			want:     map[string]bool{"serve-folder": true, "index-template": true, "tls": true, "draining": false},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "health-checks")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for f, c := range tt.files {
+				if err := os.WriteFile(path.Join(dir, f), []byte(c), 0644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+			if tt.noServeFolder {
+				dir = path.Join(dir, "does-not-exist")
+			}
+			w := &WebServer{
+				HealthPortEnabled: true,
+				HTTPSPortEnabled:  tt.httpsPortEnabled,
+				ServeFolder:       dir,
+				VueJSHistoryMode:  tt.vueJSHistoryMode,
+				tlsErr:            tt.tlsErr,
+			}
+			w.draining.Store(tt.draining)
+			h := w.NewHealthFromWebServer()
+			if !h.Enabled {
+				t.Errorf("WebServer.NewHealthFromWebServer().Enabled = %v, want %v", h.Enabled, true)
+			}
+			got := map[string]bool{}
+			for _, c := range h.ReadinessChecks {
+				got[c.Name] = c.Check() == nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WebServer.NewHealthFromWebServer() checks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebServer_Start(t *testing.T) {
 	type fields struct {
 		AppPort               string
 		HTTPAllowedOrigins    []string
@@ -1623,9 +1726,10 @@ func TestWebServer_Listen(t *testing.T) {
 		serverTLS             *http.Server
 	}
 	tests := []struct {
-		name          string
-		noQuitChannel bool
-		fields        fields
+		name      string
+		fields    fields
+		portInUse bool
+		wantErr   bool
 	}{
 		{
 			name: "basic",
@@ -1643,6 +1747,26 @@ func TestWebServer_Listen(t *testing.T) {
 				MetricsPort:      fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
 			},
 		},
+		{
+			name: "metrics and health",
+			fields: fields{
+				AppPort:            fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+				MetricsPort:        fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+				MetricsPortEnabled: true,
+				HealthPort:         fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+				HealthPortEnabled:  true,
+			},
+		},
+		{
+			name: "port in use",
+			fields: fields{
+				AppPort:            fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+				MetricsPort:        fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+				MetricsPortEnabled: true,
+			},
+			portInUse: true,
+			wantErr:   true,
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -1708,16 +1832,275 @@ func TestWebServer_Listen(t *testing.T) {
 				}
 			}
 			w.newHandlerForWebServer()
-			quitChan := make(chan bool, 1)
-			if tt.noQuitChannel {
-				quitChan = nil
+			if tt.portInUse {
+				l, err := net.Listen("tcp", w.MetricsPort)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer l.Close()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			if err := w.Start(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("WebServer.Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebServer_Shutdown(t *testing.T) {
+	w := &WebServer{
+		AppPort:              fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+		ShutdownPreStopDelay: 200 * time.Millisecond,
+		server:               &http.Server{},
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- w.Start(context.Background())
+	}()
+	for {
+		conn, err := net.DialTimeout("tcp", "localhost"+w.AppPort, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- w.Shutdown(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := w.checkNotDraining(); err == nil {
+		t.Errorf("WebServer.checkNotDraining() = nil whilst draining, want error")
+	}
+	if conn, err := net.DialTimeout("tcp", "localhost"+w.AppPort, 100*time.Millisecond); err != nil {
+		t.Errorf("expected to still be serving whilst draining: %v", err)
+	} else {
+		conn.Close()
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Errorf("WebServer.Shutdown() error = %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("WebServer.Start() error = %v", err)
+	}
+}
+
+func TestWebServer_Shutdown_restart(t *testing.T) {
+	w := New(WithAppPort(fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000)), WithServeFolder(t.TempDir()))
+	for i := 0; i < 2; i++ {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- w.Start(context.Background())
+		}()
+		for {
+			conn, err := net.DialTimeout("tcp", "localhost"+w.AppPort, 100*time.Millisecond)
+			if err == nil {
+				conn.Close()
+				break
 			}
-			go w.Listen(quitChan)
-			if !tt.noQuitChannel {
-				defer func() {
-					quitChan <- true
-				}()
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err := w.checkNotDraining(); err != nil {
+			t.Errorf("run %v: WebServer.checkNotDraining() = %v, want nil", i, err)
+		}
+		if w.upgraded.Load() {
+			t.Errorf("run %v: WebServer.upgraded = true, want reset by Start", i)
+		}
+		// shut down as after an upgrade, which the next run mustn't carry on from
+		w.upgraded.Store(true)
+		if err := w.Shutdown(context.Background()); err != nil {
+			t.Fatalf("run %v: WebServer.Shutdown() error = %v", i, err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("run %v: WebServer.Start() error = %v", i, err)
+		}
+	}
+}
+
+func TestWebServer_Listen(t *testing.T) {
+	w := New(WithAppPort(fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000)), WithServeFolder(t.TempDir()))
+	ch := make(chan bool)
+	done := make(chan struct{})
+	go func() {
+		w.Listen(ch)
+		close(done)
+	}()
+	for {
+		conn, err := net.DialTimeout("tcp", "localhost"+w.AppPort, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	ch <- true
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("WebServer.Listen() didn't return when stopped")
+	}
+}
+
+func TestWebServer_Handler(t *testing.T) {
+	dir, err := os.MkdirTemp("", "handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for f, c := range map[string]string{
+		"index.html": "hello",
+		"404.html":   "not found",
+	} {
+		if err := os.WriteFile(path.Join(dir, f), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := New(
+		WithServeFolder(dir),
+		WithRedirectRoutes(map[string]string{"/example": "https://example.com"}),
+		WithExtraHandlers(&ExtraHandler{
+			Path: "/extra",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "extra")
+			},
+			HTTPMethods: []string{http.MethodGet},
+		}),
+	)
+	mux := http.NewServeMux()
+	mux.Handle("/site/", http.StripPrefix("/site", w.Handler()))
+
+	tests := []struct {
+		name         string
+		handler      http.Handler
+		path         string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:     "file",
+			handler:  w.Handler(),
+			path:     "/",
+			wantCode: http.StatusOK,
+			wantBody: "hello",
+		},
+		{
+			name:     "not found",
+			handler:  w,
+			path:     "/nope",
+			wantCode: http.StatusNotFound,
+			wantBody: "not found",
+		},
+		{
+			name:     "extra handler",
+			handler:  w,
+			path:     "/extra",
+			wantCode: http.StatusOK,
+			wantBody: "extra",
+		},
+		{
+			name:         "redirect",
+			handler:      w,
+			path:         "/example",
+			wantCode:     http.StatusTemporaryRedirect,
+			wantLocation: "https://example.com",
+		},
+		{
+			name:     "mounted under a prefix",
+			handler:  mux,
+			path:     "/site/",
+			wantCode: http.StatusOK,
+			wantBody: "hello",
+		},
+		{
+			name:     "extra handler mounted under a prefix",
+			handler:  mux,
+			path:     "/site/extra",
+			wantCode: http.StatusOK,
+			wantBody: "extra",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("GET %v code = %v, want %v", tt.path, rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("GET %v body = %v, want %v", tt.path, rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("GET %v location = %v, want %v", tt.path, got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestWebServer_Handler_clientIP(t *testing.T) {
+	w := New(
+		WithServeFolder(t.TempDir()),
+		WithTrustedProxies("10.0.0.0/8"),
+		WithExtraHandlers(&ExtraHandler{
+			Path: "/ip",
+			HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, realip.FromRequest(r))
+			},
+			HTTPMethods: []string{http.MethodGet},
+		}),
+	)
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "192.0.2.1:1234",
+			want:       "192.0.2.1",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.1")
+			rec := httptest.NewRecorder()
+			w.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("GET /ip client IP = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   bool
+	}{
+		{path: "/admin", prefix: "/admin", want: true},
+		{path: "/admin/", prefix: "/admin", want: true},
+		{path: "/admin/users", prefix: "/admin", want: true},
+		{path: "/admin/users", prefix: "/admin/", want: true},
+		{path: "/administrator", prefix: "/admin", want: false},
+		{path: "/admin", prefix: "/admin/", want: false},
+		{path: "/anything", prefix: "/", want: true},
+	}
+	for _, tt := range tests {
+		if got := hasPathPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("hasPathPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package httpserver
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+)
+
+// ServerTimeouts are the timeouts and limits of a listener's server, overriding the
+// defaults for every listener. Unset fields use the defaults
+type ServerTimeouts struct {
+	ReadTimeout       *Duration `json:"readTimeout,omitempty"`
+	ReadHeaderTimeout *Duration `json:"readHeaderTimeout,omitempty"`
+	WriteTimeout      *Duration `json:"writeTimeout,omitempty"`
+	IdleTimeout       *Duration `json:"idleTimeout,omitempty"`
+	MaxHeaderBytes    *int      `json:"maxHeaderBytes,omitempty"`
+}
+
+// configureServer returns a function setting the timeouts and limits of the listener's server
+func (w *WebServer) configureServer(name string) func(*http.Server) {
+	t := w.ListenerTimeouts[name]
+	return func(s *http.Server) {
+		s.ReadTimeout = durationOr(t.ReadTimeout, w.ReadTimeout)
+		s.ReadHeaderTimeout = durationOr(t.ReadHeaderTimeout, w.ReadHeaderTimeout)
+		s.WriteTimeout = durationOr(t.WriteTimeout, w.WriteTimeout)
+		s.IdleTimeout = durationOr(t.IdleTimeout, w.IdleTimeout)
+		s.MaxHeaderBytes = w.MaxHeaderBytes
+		if t.MaxHeaderBytes != nil {
+			s.MaxHeaderBytes = *t.MaxHeaderBytes
+		}
+	}
+}
+
+func durationOr(d *Duration, defaultValue time.Duration) time.Duration {
+	if d == nil {
+		return defaultValue
+	}
+	return time.Duration(*d)
+}
+
+// errConnectionRejected is returned when reading from a connection over the per client IP limit
+var errConnectionRejected = errors.New("too many connections from the client IP")
+
+// connLimiter limits the number of concurrent connections, overall and per client IP,
+// shared between listeners
+type connLimiter struct {
+	max      int
+	maxPerIP int
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+func newConnLimiter(max int, maxPerIP int) *connLimiter {
+	return &connLimiter{max: max, maxPerIP: maxPerIP, perIP: map[string]int{}}
+}
+
+// acquire takes a connection from the overall limit, returning false when at the limit
+func (c *connLimiter) acquire() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max > 0 && c.total >= c.max {
+		return false
+	}
+	c.total++
+	return true
+}
+
+func (c *connLimiter) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.total--
+}
+
+// acquireIP takes a connection from the client IP's limit, returning false when at the limit
+func (c *connLimiter) acquireIP(ip string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxPerIP > 0 && c.perIP[ip] >= c.maxPerIP {
+		return false
+	}
+	c.perIP[ip]++
+	return true
+}
+
+func (c *connLimiter) releaseIP(ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.perIP[ip]--
+	if c.perIP[ip] <= 0 {
+		delete(c.perIP, ip)
+	}
+}
+
+// limitListener closes connections over the limits of its limiter, counting open connections
+type limitListener struct {
+	net.Listener
+	name    string
+	limiter *connLimiter
+}
+
+// Accept returns the next connection within the overall limit. The client IP limit is checked
+// on the first read, as the client IP may only be known once a PROXY protocol header is read
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if !l.limiter.acquire() {
+			metrics.RecordConnectionRejected(l.name, metrics.RejectReasonMaxConnections)
+			conn.Close()
+			continue
+		}
+		metrics.AddActiveConnections(l.name, 1)
+		return &limitConn{Conn: conn, listener: l}, nil
+	}
+}
+
+// limitConn releases its place in the limits when closed
+type limitConn struct {
+	net.Conn
+	listener *limitListener
+
+	checkOnce sync.Once
+	ip        string
+	rejected  bool
+	closeOnce sync.Once
+}
+
+func (c *limitConn) Read(b []byte) (int, error) {
+	c.checkOnce.Do(func() {
+		addr, err := netip.ParseAddrPort(c.RemoteAddr().String())
+		if err != nil {
+			// connections over unix sockets and systemd listeners without a PROXY protocol
+			// header have no client IP, and sharing one limit would reject every client
+			return
+		}
+		ip := addr.Addr().Unmap().String()
+		if !c.listener.limiter.acquireIP(ip) {
+			c.rejected = true
+			metrics.RecordConnectionRejected(c.listener.name, metrics.RejectReasonMaxConnectionsPerIP)
+			return
+		}
+		c.ip = ip
+	})
+	if c.rejected {
+		c.Close()
+		return 0, errConnectionRejected
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *limitConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.checkOnce.Do(func() {})
+		if c.ip != "" {
+			c.listener.limiter.releaseIP(c.ip)
+		}
+		c.listener.limiter.release()
+		metrics.AddActiveConnections(c.listener.name, -1)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,189 @@
+package httpserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestWebServer_configureServer(t *testing.T) {
+	type settings struct {
+		read, readHeader, write, idle time.Duration
+		maxHeaderBytes                int
+	}
+	ws := New(
+		WithServerTimeouts(ServerTimeouts{
+			ReadTimeout:       pointer(Duration(time.Minute)),
+			ReadHeaderTimeout: pointer(Duration(5 * time.Second)),
+			WriteTimeout:      pointer(Duration(0)),
+			IdleTimeout:       pointer(Duration(30 * time.Second)),
+			MaxHeaderBytes:    pointer(4096),
+		}),
+		WithListenerTimeouts(map[string]ServerTimeouts{
+			"metrics": {WriteTimeout: pointer(Duration(10 * time.Second)), MaxHeaderBytes: pointer(1024)},
+		}),
+	)
+	tests := []struct {
+		name     string
+		listener string
+		want     settings
+	}{
+		{
+			name:     "defaults",
+			listener: "http",
+			want:     settings{read: time.Minute, readHeader: 5 * time.Second, idle: 30 * time.Second, maxHeaderBytes: 4096},
+		},
+		{
+			name:     "overridden by listener",
+			listener: "metrics",
+			want:     settings{read: time.Minute, readHeader: 5 * time.Second, write: 10 * time.Second, idle: 30 * time.Second, maxHeaderBytes: 1024},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := &http.Server{}
+			ws.configureServer(tt.listener)(s)
+			got := settings{s.ReadTimeout, s.ReadHeaderTimeout, s.WriteTimeout, s.IdleTimeout, s.MaxHeaderBytes}
+			if got != tt.want {
+				t.Errorf("WebServer.configureServer(%v) = %+v, want %+v", tt.listener, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebServer_listen_maxConnections(t *testing.T) {
+	tests := []struct {
+		name                string
+		unix                bool
+		proxyProtocol       bool
+		headers             []string
+		maxConnections      int
+		maxConnectionsPerIP int
+		wantUnlimited       bool
+	}{
+		{
+			name:           "overall",
+			maxConnections: 1,
+		},
+		{
+			name:                "per client IP",
+			maxConnectionsPerIP: 1,
+		},
+		{
+			name:                "per client IP on a unix socket without a client IP",
+			unix:                true,
+			maxConnectionsPerIP: 1,
+			wantUnlimited:       true,
+		},
+		{
+			name:                "per client IP from the PROXY protocol",
+			proxyProtocol:       true,
+			headers:             []string{"PROXY TCP4 198.51.100.1 127.0.0.1 4321 80\r\n", "PROXY TCP4 198.51.100.1 127.0.0.1 4322 80\r\n"},
+			maxConnectionsPerIP: 1,
+		},
+		{
+			name:                "per client IP from the PROXY protocol of different clients",
+			proxyProtocol:       true,
+			headers:             []string{"PROXY TCP4 198.51.100.1 127.0.0.1 4321 80\r\n", "PROXY TCP4 198.51.100.2 127.0.0.1 4321 80\r\n"},
+			maxConnectionsPerIP: 1,
+			wantUnlimited:       true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			appPort := "127.0.0.1:0"
+			if tt.unix {
+				appPort = "unix:" + path.Join(t.TempDir(), "ghs.sock")
+			}
+			opts := []Option{
+				WithServeFolder(t.TempDir()),
+				WithAppPort(appPort),
+				WithMaxConnections(tt.maxConnections, tt.maxConnectionsPerIP),
+			}
+			if tt.proxyProtocol {
+				opts = append(opts, WithProxyProtocol("127.0.0.1"))
+			}
+			ws := New(opts...)
+			ws.accessLogOut = io.Discard
+			ws.build()
+			servers, err := ws.listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := servers[0].listener.Addr()
+			go func() { _ = ws.server.Serve(servers[0].listener) }()
+			defer ws.server.Close()
+			dial := func(i int) net.Conn {
+				conn, err := net.Dial(addr.Network(), addr.String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i < len(tt.headers) {
+					if _, err := fmt.Fprint(conn, tt.headers[i]); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return conn
+			}
+
+			// the first connection is kept alive, holding its place in the limit
+			first := dial(0)
+			defer first.Close()
+			if _, err := get(first); err != nil {
+				t.Fatalf("GET on the first connection error = %v", err)
+			}
+			second := dial(1)
+			defer second.Close()
+			resp, err := get(second)
+			if tt.wantUnlimited {
+				if err != nil {
+					t.Fatalf("GET on the second connection error = %v, want it within the limits", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("GET on the second connection = %v, want the connection closed", resp.Status)
+			}
+			first.Close()
+			// once closed, a new connection is within the limit
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				third := dial(0)
+				_, err = get(third)
+				third.Close()
+				if err == nil {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("GET after closing the first connection error = %v", err)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+// get sends a keep-alive request on the connection, returning the response
+func get(conn net.Conn) (*http.Response, error) {
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"); err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp, nil
+}
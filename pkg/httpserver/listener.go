@@ -0,0 +1,194 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"github.com/pires/go-proxyproto"
+)
+
+// proxyProtocolListener wraps the listener to read PROXY protocol v1 and v2 headers,
+// only from the trusted CIDRs. Connections from elsewhere are served without a header,
+// and closed when they send one
+func (w *WebServer) proxyProtocolListener(l net.Listener) (net.Listener, error) {
+	policy, err := proxyproto.StrictWhiteListPolicy(w.ProxyProtocolTrustedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	return &proxyproto.Listener{Listener: l, Policy: policy}, nil
+}
+
+// validateProxyProtocol ensures that the trusted CIDRs are valid, and set when the PROXY protocol is enabled
+func (w *WebServer) validateProxyProtocol() error {
+	if !w.ProxyProtocolEnabled {
+		return nil
+	}
+	if len(w.ProxyProtocolTrustedCIDRs) == 0 {
+		return fmt.Errorf("the PROXY protocol requires trusted CIDRs")
+	}
+	if _, err := proxyproto.StrictWhiteListPolicy(w.ProxyProtocolTrustedCIDRs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// listener address schemes, for addresses other than TCP
+const (
+	listenerSchemeUnix    = "unix:"
+	listenerSchemeSystemd = "systemd:"
+)
+
+// isListenerScheme returns if the address has a listener address scheme, rather than being a TCP address
+func isListenerScheme(addr string) bool {
+	return strings.HasPrefix(addr, listenerSchemeUnix) || strings.HasPrefix(addr, listenerSchemeSystemd)
+}
+
+// listenAddr listens on a TCP address, a Unix socket as unix:/path, or a socket
+// passed by systemd socket activation as systemd:name
+func (w *WebServer) listenAddr(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, listenerSchemeUnix):
+		return w.listenUnix(strings.TrimPrefix(addr, listenerSchemeUnix))
+	case strings.HasPrefix(addr, listenerSchemeSystemd):
+		return w.listenSystemd(strings.TrimPrefix(addr, listenerSchemeSystemd))
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix listens on a Unix socket, replacing a stale socket and setting its mode and ownership
+func (w *WebServer) listenUnix(socketPath string) (net.Listener, error) {
+	if info, err := os.Lstat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		// a socket left by a process which didn't exit cleanly
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %v is in use", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.chmodUnixSocket(socketPath); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// chmodUnixSocket sets the mode and ownership of a Unix socket, when configured
+func (w *WebServer) chmodUnixSocket(socketPath string) error {
+	if w.UnixSocketMode != "" {
+		mode, err := parseFileMode(w.UnixSocketMode)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(socketPath, mode); err != nil {
+			return err
+		}
+	}
+	if w.UnixSocketUser == "" && w.UnixSocketGroup == "" {
+		return nil
+	}
+	uid, gid, err := lookupOwner(w.UnixSocketUser, w.UnixSocketGroup)
+	if err != nil {
+		return err
+	}
+	return os.Chown(socketPath, uid, gid)
+}
+
+// parseFileMode parses an octal file mode, such as 0660
+func parseFileMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid unix socket mode '%v', expected octal permissions such as 0660", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// lookupOwner returns the ids of a user and group, by name or id, or -1 when unset
+func lookupOwner(username string, group string) (uid int, gid int, err error) {
+	uid, gid = -1, -1
+	if username != "" {
+		if uid, err = strconv.Atoi(username); err != nil {
+			u, err := user.Lookup(username)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid unix socket user: %w", err)
+			}
+			if uid, err = strconv.Atoi(u.Uid); err != nil {
+				return 0, 0, fmt.Errorf("invalid unix socket user: %w", err)
+			}
+		}
+	}
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid unix socket group: %w", err)
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return 0, 0, fmt.Errorf("invalid unix socket group: %w", err)
+			}
+		}
+	}
+	return uid, gid, nil
+}
+
+// listenSystemd returns the socket passed by systemd with the name, given by FileDescriptorName
+// in the socket unit. Sockets are read from LISTEN_FDS once, being shared between servers
+func (w *WebServer) listenSystemd(name string) (net.Listener, error) {
+	w.systemdOnce.Do(func() {
+		listenersWithNames := w.systemdListenersWithNames
+		if listenersWithNames == nil {
+			listenersWithNames = activation.ListenersWithNames
+		}
+		w.systemdListeners, w.systemdErr = listenersWithNames()
+	})
+	if w.systemdErr != nil {
+		return nil, fmt.Errorf("failed to find systemd sockets: %w", w.systemdErr)
+	}
+	listeners := w.systemdListeners[name]
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("no socket named '%v' was passed by systemd", name)
+	}
+	l := listeners[0]
+	w.systemdListeners[name] = listeners[1:]
+	return l, nil
+}
+
+// validateListeners ensures that the listener addresses, Unix socket settings and limits are valid
+func (w *WebServer) validateListeners() error {
+	var errs []error
+	for _, addr := range []string{w.AppPort, w.HTTPSPort, w.MetricsPort, w.HealthPort} {
+		switch {
+		case addr == listenerSchemeUnix:
+			errs = append(errs, fmt.Errorf("invalid listener address '%v', expected unix:/path", addr))
+		case addr == listenerSchemeSystemd:
+			errs = append(errs, fmt.Errorf("invalid listener address '%v', expected systemd:name", addr))
+		}
+	}
+	if w.UnixSocketMode != "" {
+		if _, err := parseFileMode(w.UnixSocketMode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for name := range w.ListenerTimeouts {
+		switch name {
+		case "http", "https", "metrics", "health":
+		default:
+			errs = append(errs, fmt.Errorf("invalid listener '%v' in listener timeouts, expected one of http, https, metrics or health", name))
+		}
+	}
+	if w.MaxConnections < 0 || w.MaxConnectionsPerIP < 0 {
+		errs = append(errs, fmt.Errorf("the maximum connections must not be negative"))
+	}
+	return errors.Join(errs...)
+}
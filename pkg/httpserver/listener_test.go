@@ -0,0 +1,190 @@
+package httpserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestWebServer_listen_proxyProtocol(t *testing.T) {
+	tests := []struct {
+		name         string
+		trustedCIDRs []string
+		header       string
+		wantCode     int
+		wantIP       string
+	}{
+		{
+			name:         "v1 from a trusted source",
+			trustedCIDRs: []string{"127.0.0.0/8"},
+			header:       "PROXY TCP4 198.51.100.1 127.0.0.1 4321 80\r\n",
+			wantCode:     http.StatusOK,
+			wantIP:       "198.51.100.1",
+		},
+		{
+			name:         "v2 from a trusted source",
+			trustedCIDRs: []string{"127.0.0.1"},
+			header: "\r\n\r\n\x00\r\nQUIT\n" + // signature
+				"\x21\x11\x00\x0c" + // PROXY over TCP4, with 12 bytes of addresses
+				"\xc6\x33\x64\x02" + "\x7f\x00\x00\x01" + "\x10\xe1" + "\x00\x50",
+			wantCode: http.StatusOK,
+			wantIP:   "198.51.100.2",
+		},
+		{
+			name:         "no header from a trusted source",
+			trustedCIDRs: []string{"127.0.0.0/8"},
+			wantCode:     http.StatusOK,
+			wantIP:       "127.0.0.1",
+		},
+		{
+			name:         "header from an untrusted source",
+			trustedCIDRs: []string{"10.0.0.0/8"},
+			header:       "PROXY TCP4 198.51.100.1 127.0.0.1 4321 80\r\n",
+			wantCode:     http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ws := New(
+				WithServeFolder(t.TempDir()),
+				WithAppPort("127.0.0.1:0"),
+				WithProxyProtocol(tt.trustedCIDRs...),
+				WithExtraHandlers(&ExtraHandler{
+					Path: "/ip",
+					HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+						host, _, _ := net.SplitHostPort(r.RemoteAddr)
+						fmt.Fprint(w, host)
+					},
+					HTTPMethods: []string{http.MethodGet},
+				}),
+			)
+			ws.accessLogOut = io.Discard
+			ws.build()
+			servers, err := ws.listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := servers[0].listener
+			go func() { _ = ws.server.Serve(l) }()
+			defer ws.server.Close()
+
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			fmt.Fprintf(conn, "%vGET /ip HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n", tt.header)
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("GET /ip code = %v, want %v", resp.StatusCode, tt.wantCode)
+			}
+			if tt.wantIP == "" {
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.wantIP {
+				t.Errorf("GET /ip remote address = %v, want %v", string(body), tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestWebServer_listenAddr(t *testing.T) {
+	systemdListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer systemdListener.Close()
+	tests := []struct {
+		name     string
+		addr     func(dir string) string
+		setup    func(t *testing.T, dir string)
+		wantErr  bool
+		wantMode os.FileMode
+	}{
+		{
+			name:     "unix socket",
+			addr:     func(dir string) string { return "unix:" + path.Join(dir, "ghs.sock") },
+			wantMode: 0660,
+		},
+		{
+			name: "stale unix socket",
+			addr: func(dir string) string { return "unix:" + path.Join(dir, "ghs.sock") },
+			setup: func(t *testing.T, dir string) {
+				l, err := net.Listen("unix", path.Join(dir, "ghs.sock"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				l.(*net.UnixListener).SetUnlinkOnClose(false)
+				l.Close()
+			},
+			wantMode: 0660,
+		},
+		{
+			name: "unix socket in use",
+			addr: func(dir string) string { return "unix:" + path.Join(dir, "ghs.sock") },
+			setup: func(t *testing.T, dir string) {
+				l, err := net.Listen("unix", path.Join(dir, "ghs.sock"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { l.Close() })
+			},
+			wantErr: true,
+		},
+		{
+			name: "systemd socket",
+			addr: func(dir string) string { return "systemd:http" },
+		},
+		{
+			name:    "missing systemd socket",
+			addr:    func(dir string) string { return "systemd:https" },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+			ws := New(WithUnixSocketOwnership("0660", "", ""))
+			ws.systemdListenersWithNames = func() (map[string][]net.Listener, error) {
+				return map[string][]net.Listener{"http": {systemdListener}}, nil
+			}
+			l, err := ws.listenAddr(tt.addr(dir))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WebServer.listenAddr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if l.Addr().Network() == "unix" {
+				defer l.Close()
+			}
+			if tt.wantMode == 0 {
+				return
+			}
+			info, err := os.Stat(l.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.wantMode {
+				t.Errorf("WebServer.listenAddr() mode = %v, want %v", got, tt.wantMode)
+			}
+		})
+	}
+}
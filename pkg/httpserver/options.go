@@ -0,0 +1,372 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
+)
+
+// Option configures a WebServer
+type Option func(*WebServer)
+
+// FromEnv configures the WebServer from the environment variables,
+// and from the config file at APP_CONFIG_PATH when it is set
+func FromEnv() Option {
+	return func(w *WebServer) {
+		w.addConfig(SourceDefault, ConfigFromEnv(false))
+		w.addConfig(SourceEnv, ConfigFromEnv(true))
+		w.envConfigPath = common.GetConfigPath()
+	}
+}
+
+// WithConfigFile configures the WebServer from a YAML or JSON config file,
+// in place of any config file set by APP_CONFIG_PATH
+func WithConfigFile(path string) Option {
+	return func(w *WebServer) {
+		w.configPath = path
+	}
+}
+
+// WithConfig configures the WebServer with the fields set in the config, from a source
+func WithConfig(source Source, cfg *Config) Option {
+	return func(w *WebServer) {
+		w.addConfig(source, cfg)
+	}
+}
+
+// WithServeFolder sets the folder to serve
+func WithServeFolder(path string) Option {
+	return WithConfig(SourceOption, &Config{ServeFolder: &path})
+}
+
+// WithAppPort sets the address to serve HTTP on
+func WithAppPort(addr string) Option {
+	return WithConfig(SourceOption, &Config{AppPort: &addr})
+}
+
+// WithHTTPS enables serving HTTPS on the address, with a certificate and key from files
+func WithHTTPS(addr string, certPath string, keyPath string) Option {
+	return WithConfig(SourceOption, &Config{
+		HTTPSPortEnabled: pointer(true),
+		HTTPSPort:        &addr,
+		TLSCertPath:      &certPath,
+		TLSKeyPath:       &keyPath,
+	})
+}
+
+// WithTLSCertificates adds certificates to serve HTTPS with, chosen by server name,
+// along with the certificates in the directory when it is set. Without a match, the first
+// certificate is served, or the handshake rejected when rejectUnknownSNI is set
+func WithTLSCertificates(dir string, rejectUnknownSNI bool, certificates ...TLSCertificate) Option {
+	return WithConfig(SourceOption, &Config{
+		TLSCertDir:          &dir,
+		TLSCertificates:     &certificates,
+		TLSRejectUnknownSNI: &rejectUnknownSNI,
+	})
+}
+
+// WithH2C serves HTTP/2 without TLS on the app port, with the stream limits, which use the defaults when zero
+func WithH2C(maxConcurrentStreams int, maxStreamBuffer int) Option {
+	return WithConfig(SourceOption, &Config{
+		H2CEnabled:              pointer(true),
+		H2CMaxConcurrentStreams: &maxConcurrentStreams,
+		H2CMaxStreamBuffer:      &maxStreamBuffer,
+	})
+}
+
+// WithHTTP3 serves HTTP/3 over QUIC on the UDP port of the HTTPS port, advertised from HTTPS with the Alt-Svc header
+func WithHTTP3() Option {
+	return WithConfig(SourceOption, &Config{HTTP3Enabled: pointer(true)})
+}
+
+// WithHTTPSRedirect redirects plain HTTP requests to HTTPS, at the origin when given,
+// otherwise on the HTTPS port of the requested host
+func WithHTTPSRedirect(origin string) Option {
+	return WithConfig(SourceOption, &Config{HTTPSRedirect: pointer(true), HTTPSRedirectOrigin: &origin})
+}
+
+// WithHSTS sets the Strict-Transport-Security header on HTTPS responses, with the max age
+func WithHSTS(maxAge time.Duration) Option {
+	return WithConfig(SourceOption, &Config{HSTSMaxAge: pointer(Duration(maxAge))})
+}
+
+// WithHSTSIncludeSubDomains sets whether the Strict-Transport-Security header includes subdomains
+func WithHSTSIncludeSubDomains(enabled bool) Option {
+	return WithConfig(SourceOption, &Config{HSTSIncludeSubDomains: &enabled})
+}
+
+// WithHSTSPreload sets whether the Strict-Transport-Security header allows preloading
+func WithHSTSPreload(enabled bool) Option {
+	return WithConfig(SourceOption, &Config{HSTSPreload: &enabled})
+}
+
+// WithTLSPolicy sets the protocol versions, cipher suites, curves, session tickets and ALPN protocols of HTTPS,
+// from the fields set in the policy
+func WithTLSPolicy(policy TLSPolicy) Option {
+	cfg := &Config{}
+	if policy.Preset != "" {
+		cfg.TLSPreset = &policy.Preset
+	}
+	if policy.MinVersion != "" {
+		cfg.TLSMinVersion = &policy.MinVersion
+	}
+	if policy.MaxVersion != "" {
+		cfg.TLSMaxVersion = &policy.MaxVersion
+	}
+	if policy.SessionTicketsDisabled {
+		cfg.TLSSessionTicketsDisabled = &policy.SessionTicketsDisabled
+	}
+	if policy.CipherSuites != nil {
+		cfg.TLSCipherSuites = &policy.CipherSuites
+	}
+	if policy.Curves != nil {
+		cfg.TLSCurves = &policy.Curves
+	}
+	if policy.ALPN != nil {
+		cfg.TLSALPN = &policy.ALPN
+	}
+	return WithConfig(SourceOption, cfg)
+}
+
+// WithTLSClientAuth sets the client certificate authentication of HTTPS, as one of the ClientAuth modes,
+// with the CA bundles to verify client certificates with. Rules require a verified client certificate
+// for path prefixes, which verifies client certificates when given
+func WithTLSClientAuth(mode string, caPaths []string, rules ...TLSClientAuthRule) Option {
+	return WithConfig(SourceOption, &Config{
+		TLSClientAuth:      &mode,
+		TLSClientCAPaths:   &caPaths,
+		TLSClientAuthRules: &rules,
+	})
+}
+
+// WithACME obtains and renews certificates for the hostnames from the ACME directory, such as
+// Let's Encrypt, storing them in the cache directory. HTTPS must be enabled
+func WithACME(directoryURL string, cacheDir string, email string, hostnames ...string) Option {
+	return WithConfig(SourceOption, &Config{
+		ACMEEnabled:      pointer(true),
+		ACMEDirectoryURL: &directoryURL,
+		ACMECacheDir:     &cacheDir,
+		ACMEEmail:        &email,
+		ACMEHostnames:    &hostnames,
+	})
+}
+
+// WithTLSConfig sets the TLS config to serve HTTPS with, in place of loading certificate files or ACME.
+// The TLS policy, client certificate authentication and handshake metrics are applied to a copy of it,
+// keeping its own values where they're unset
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(w *WebServer) {
+		w.tlsConfig = cfg
+	}
+}
+
+// WithReloadOnSignal reloads the config and TLS certificates on SIGHUP, whilst started.
+// Without it, no signal handler is installed and reloads are left to Reload and the reload interval
+func WithReloadOnSignal() Option {
+	return func(w *WebServer) {
+		w.ReloadOnSignal = true
+	}
+}
+
+// WithUpgradeOnSignal upgrades to a new process on SIGUSR2, whilst started, shutting down once it is serving.
+// Without it, no signal handler is installed and upgrades are left to Upgrade
+func WithUpgradeOnSignal() Option {
+	return func(w *WebServer) {
+		w.UpgradeOnSignal = true
+	}
+}
+
+// WithMetricsPort enables serving metrics on the address
+func WithMetricsPort(addr string) Option {
+	return WithConfig(SourceOption, &Config{MetricsPortEnabled: pointer(true), MetricsPort: &addr})
+}
+
+// WithHealthPort enables serving health on the address
+func WithHealthPort(addr string) Option {
+	return WithConfig(SourceOption, &Config{HealthPortEnabled: pointer(true), HealthPort: &addr})
+}
+
+// WithVueJSHistoryMode sets whether to rewrite requests, except for assets, to index.html
+func WithVueJSHistoryMode(enabled bool) Option {
+	return WithConfig(SourceOption, &Config{VueJSHistoryMode: &enabled})
+}
+
+// WithGzip sets whether to gzip responses
+func WithGzip(enabled bool) Option {
+	return WithConfig(SourceOption, &Config{GzipEnabled: &enabled})
+}
+
+// WithError404FilePath sets the file to serve when a file is not found
+func WithError404FilePath(path string) Option {
+	return WithConfig(SourceOption, &Config{Error404FilePath: &path})
+}
+
+// WithHeaderMap sets headers to add to responses
+func WithHeaderMap(headerMap map[string][]string) Option {
+	return WithConfig(SourceOption, &Config{HeaderMapEnabled: pointer(true), HeaderMap: &headerMap})
+}
+
+// WithHeaderMapPath sets the path to load headers to add to responses from
+func WithHeaderMapPath(path string) Option {
+	return WithConfig(SourceOption, &Config{HeaderMapEnabled: pointer(true), HeaderMapPath: &path})
+}
+
+// WithTemplateMap sets the values to template index.html with
+func WithTemplateMap(templateMap map[string]string) Option {
+	return WithConfig(SourceOption, &Config{TemplateMap: &templateMap})
+}
+
+// WithTemplateMapPath sets the path to load values to template index.html with from
+func WithTemplateMapPath(path string) Option {
+	return WithConfig(SourceOption, &Config{TemplateMapPath: &path})
+}
+
+// WithRedirectRoutes sets paths to redirect to other URLs
+func WithRedirectRoutes(redirectRoutes map[string]string) Option {
+	return WithConfig(SourceOption, &Config{RedirectRoutesEnabled: pointer(true), RedirectRoutes: &redirectRoutes})
+}
+
+// WithRedirectRoutesPath sets the path to load paths to redirect to other URLs from
+func WithRedirectRoutesPath(path string) Option {
+	return WithConfig(SourceOption, &Config{RedirectRoutesEnabled: pointer(true), RedirectRoutesPath: &path})
+}
+
+// WithHTTPAllowedOrigins sets the origins allowed by CORS
+func WithHTTPAllowedOrigins(origins ...string) Option {
+	return WithConfig(SourceOption, &Config{HTTPAllowedOrigins: &origins})
+}
+
+// WithUnixSocketOwnership sets the octal file mode, such as 0660, and the user and group,
+// by name or id, of Unix sockets listened on with unix:/path addresses
+func WithUnixSocketOwnership(mode string, username string, group string) Option {
+	return WithConfig(SourceOption, &Config{
+		UnixSocketMode:  &mode,
+		UnixSocketUser:  &username,
+		UnixSocketGroup: &group,
+	})
+}
+
+// WithProxyProtocol accepts PROXY protocol v1 and v2 headers on the HTTP and HTTPS ports,
+// from the trusted CIDRs or IPs only
+func WithProxyProtocol(trustedCIDRs ...string) Option {
+	return WithConfig(SourceOption, &Config{
+		ProxyProtocolEnabled:      pointer(true),
+		ProxyProtocolTrustedCIDRs: &trustedCIDRs,
+	})
+}
+
+// WithTrustedProxies sets the CIDRs or IPs of proxies trusted to set the real IP header
+func WithTrustedProxies(trustedProxies ...string) Option {
+	return WithConfig(SourceOption, &Config{TrustedProxies: &trustedProxies})
+}
+
+// WithRealIPHeader sets the header to use for the client IP, in place of the remote address
+func WithRealIPHeader(header string) Option {
+	return WithConfig(SourceOption, &Config{RealIPHeader: &header})
+}
+
+// WithReloadInterval sets the interval to check config files for changes to reload, or 0 to disable
+func WithReloadInterval(interval time.Duration) Option {
+	return WithConfig(SourceOption, &Config{ReloadInterval: pointer(Duration(interval))})
+}
+
+// WithAccessLog sets the format of access logs, one of json, combined or template,
+// with the Go template of an access log entry for the template format
+func WithAccessLog(format string, template string) Option {
+	return WithConfig(SourceOption, &Config{AccessLogFormat: &format, AccessLogTemplate: &template})
+}
+
+// WithAccessLogRedaction sets headers to redact from access logs, along with the default sensitive
+// headers, the only headers to log when allowHeaders is set, and query parameters to mask
+func WithAccessLogRedaction(redactHeaders []string, allowHeaders []string, maskQueryParams []string) Option {
+	return WithConfig(SourceOption, &Config{
+		AccessLogRedactHeaders:   &redactHeaders,
+		AccessLogAllowHeaders:    &allowHeaders,
+		AccessLogMaskQueryParams: &maskQueryParams,
+	})
+}
+
+// WithCORS sets the CORS policy for every path, from the fields set in the policy
+func WithCORS(policy CORSPolicy) Option {
+	cfg := &Config{
+		CORSAllowCredentials:    policy.AllowCredentials,
+		CORSMaxAge:              policy.MaxAge,
+		CORSAllowPrivateNetwork: policy.AllowPrivateNetwork,
+	}
+	if policy.AllowedOrigins != nil {
+		cfg.HTTPAllowedOrigins = &policy.AllowedOrigins
+	}
+	if policy.AllowedMethods != nil {
+		cfg.CORSAllowedMethods = &policy.AllowedMethods
+	}
+	if policy.AllowedHeaders != nil {
+		cfg.CORSAllowedHeaders = &policy.AllowedHeaders
+	}
+	if policy.ExposedHeaders != nil {
+		cfg.CORSExposedHeaders = &policy.ExposedHeaders
+	}
+	return WithConfig(SourceOption, cfg)
+}
+
+// WithCORSRules sets CORS policies for path prefixes, in place of the policy for every path
+func WithCORSRules(rules ...CORSRule) Option {
+	return WithConfig(SourceOption, &Config{CORSRules: &rules})
+}
+
+// WithLogging sets the level and format of logs, used when the binary sets the default logger
+func WithLogging(level string, format string) Option {
+	return WithConfig(SourceOption, &Config{LogLevel: &level, LogFormat: &format})
+}
+
+// WithExtraHandlers adds extra http handlers
+func WithExtraHandlers(hs ...*ExtraHandler) Option {
+	return func(w *WebServer) {
+		w.ExtraHandlers = append(w.ExtraHandlers, hs...)
+	}
+}
+
+// WithExtraMiddleware adds extra http middleware
+func WithExtraMiddleware(m ...func(http.Handler) http.Handler) Option {
+	return func(w *WebServer) {
+		w.ExtraMiddleware = append(w.ExtraMiddleware, m...)
+	}
+}
+
+// WithServerTimeouts sets the timeouts and maximum header size of every listener's server,
+// from the fields set in timeouts, where a timeout of 0 is no timeout
+func WithServerTimeouts(timeouts ServerTimeouts) Option {
+	return WithConfig(SourceOption, &Config{
+		ReadTimeout:       timeouts.ReadTimeout,
+		ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+		WriteTimeout:      timeouts.WriteTimeout,
+		IdleTimeout:       timeouts.IdleTimeout,
+		MaxHeaderBytes:    timeouts.MaxHeaderBytes,
+	})
+}
+
+// WithListenerTimeouts sets the timeouts and limits of each listener's server, by listener name
+// of http, https, metrics or health, overriding those of WithServerTimeouts
+func WithListenerTimeouts(timeouts map[string]ServerTimeouts) Option {
+	return WithConfig(SourceOption, &Config{ListenerTimeouts: &timeouts})
+}
+
+// WithMaxConnections sets the maximum number of concurrent HTTP and HTTPS connections,
+// overall and per client IP, where 0 is no limit
+func WithMaxConnections(maxConnections int, maxConnectionsPerIP int) Option {
+	return WithConfig(SourceOption, &Config{
+		MaxConnections:      &maxConnections,
+		MaxConnectionsPerIP: &maxConnectionsPerIP,
+	})
+}
+
+// WithShutdownPreStopDelay sets the time to keep serving whilst draining, before shutting down
+func WithShutdownPreStopDelay(delay time.Duration) Option {
+	return WithConfig(SourceOption, &Config{ShutdownPreStopDelay: pointer(Duration(delay))})
+}
+
+// WithShutdownGraceTimeout sets the time given to in-flight requests when shutting down
+func WithShutdownGraceTimeout(timeout time.Duration) Option {
+	return WithConfig(SourceOption, &Config{ShutdownGraceTimeout: pointer(Duration(timeout))})
+}
@@ -0,0 +1,205 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		opts      []Option
+		findValue func(*WebServer) any
+		want      any
+	}{
+		{
+			name: "defaults",
+			findValue: func(ws *WebServer) any {
+				return []any{ws.AppPort, ws.HTTPSPortEnabled, ws.MetricsPortEnabled, ws.HealthPortEnabled, ws.ServeFolder}
+			},
+			want: []any{":8080", false, false, false, "."},
+		},
+		{
+			name: "env is not read",
+			env: map[string]string{
+				"APP_PORT":               ":8123",
+				"APP_VUEJS_HISTORY_MODE": "true",
+			},
+			findValue: func(ws *WebServer) any {
+				return []any{ws.AppPort, ws.VueJSHistoryMode}
+			},
+			want: []any{":8080", false},
+		},
+		{
+			name: "env is read with FromEnv",
+			env: map[string]string{
+				"APP_PORT":               ":8123",
+				"APP_VUEJS_HISTORY_MODE": "true",
+			},
+			opts: []Option{FromEnv()},
+			findValue: func(ws *WebServer) any {
+				return []any{ws.AppPort, ws.VueJSHistoryMode}
+			},
+			want: []any{":8123", true},
+		},
+		{
+			name: "options override env",
+			env: map[string]string{
+				"APP_PORT": ":8123",
+			},
+			opts: []Option{FromEnv(), WithAppPort(":8124")},
+			findValue: func(ws *WebServer) any {
+				return ws.AppPort
+			},
+			want: ":8124",
+		},
+		{
+			name: "options only set the fields given",
+			env: map[string]string{
+				"APP_HSTS_INCLUDE_SUBDOMAINS": "true",
+				"APP_HSTS_PRELOAD":            "true",
+				"APP_HTTPS_MIN_VERSION":       "1.3",
+				"APP_READ_TIMEOUT":            "1m",
+				"APP_SHUTDOWN_GRACE_TIMEOUT":  "20s",
+			},
+			opts: []Option{
+				FromEnv(),
+				WithHSTS(2 * 365 * 24 * time.Hour),
+				WithTLSPolicy(TLSPolicy{MaxVersion: "1.3"}),
+				WithServerTimeouts(ServerTimeouts{WriteTimeout: pointer(Duration(0))}),
+				WithShutdownPreStopDelay(3 * time.Second),
+			},
+			findValue: func(ws *WebServer) any {
+				return []any{
+					ws.HSTSMaxAge, ws.HSTSIncludeSubDomains, ws.HSTSPreload,
+					ws.TLSMinVersion, ws.TLSMaxVersion,
+					ws.ReadTimeout, ws.WriteTimeout,
+					ws.ShutdownPreStopDelay, ws.ShutdownGraceTimeout,
+				}
+			},
+			want: []any{
+				2 * 365 * 24 * time.Hour, true, true,
+				"1.3", "1.3",
+				time.Minute, time.Duration(0),
+				3 * time.Second, 20 * time.Second,
+			},
+		},
+		{
+			name: "options",
+			opts: []Option{
+				WithServeFolder("./site"),
+				WithHTTPS(":8444", "tls.crt", "tls.key"),
+				WithMetricsPort(":2113"),
+				WithHealthPort(":8082"),
+				WithVueJSHistoryMode(true),
+				WithGzip(false),
+				WithError404FilePath("not-found.html"),
+				WithHeaderMap(map[string][]string{"X-Abc": {"a"}}),
+				WithTemplateMap(map[string]string{"A": "B"}),
+				WithRedirectRoutes(map[string]string{"/a": "/b"}),
+				WithHTTPAllowedOrigins("https://example.com"),
+				WithShutdownPreStopDelay(time.Second),
+				WithShutdownGraceTimeout(2 * time.Second),
+			},
+			findValue: func(ws *WebServer) any {
+				return []any{
+					ws.ServeFolder,
+					ws.HTTPSPortEnabled, ws.HTTPSPort, ws.TLSCertPath, ws.TLSKeyPath,
+					ws.MetricsPortEnabled, ws.MetricsPort,
+					ws.HealthPortEnabled, ws.HealthPort,
+					ws.VueJSHistoryMode, ws.GzipEnabled, ws.Error404FilePath,
+					ws.HeaderMapEnabled, ws.HeaderMap, ws.TemplateMap, ws.RedirectRoutes,
+					ws.HTTPAllowedOrigins, ws.ShutdownPreStopDelay, ws.ShutdownGraceTimeout,
+				}
+			},
+			want: []any{
+				"./site",
+				true, ":8444", "tls.crt", "tls.key",
+				true, ":2113",
+				true, ":8082",
+				true, false, "not-found.html",
+				true, map[string][]string{"X-Abc": {"a"}}, map[string]string{"A": "B"}, map[string]string{"/a": "/b"},
+				[]string{"https://example.com"}, time.Second, 2 * time.Second,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		// NOTE sets env and cannot be parallelised
+		t.Run(tt.name, func(t *testing.T) {
+			prevEnv := map[string]string{}
+			for k, v := range tt.env {
+				prevEnv[k] = os.Getenv(k)
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k, v := range prevEnv {
+					os.Setenv(k, v)
+				}
+			}()
+			if got := tt.findValue(New(tt.opts...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_routingAssembledAtStart(t *testing.T) {
+	dir, err := os.MkdirTemp("", "new-routing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(path.Join(dir, "index.html"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	port := fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000)
+	w := New(WithAppPort(port))
+	w.SetServeFolder(dir)
+	w.SetExtraHandlers(&ExtraHandler{
+		Path: "/extra",
+		HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "extra")
+		},
+		HTTPMethods: []string{http.MethodGet},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- w.Start(ctx)
+	}()
+	for {
+		conn, err := net.DialTimeout("tcp", "localhost"+port, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	for p, want := range map[string]string{"/": "hello", "/extra": "extra"} {
+		resp, err := http.Get("http://localhost" + port + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != want {
+			t.Errorf("GET %v = %v, want %v", p, string(body), want)
+		}
+	}
+	cancel()
+	if err := <-errs; err != nil {
+		t.Errorf("WebServer.Start() error = %v", err)
+	}
+}
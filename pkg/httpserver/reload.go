@@ -0,0 +1,185 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+)
+
+// fileConfig is the configuration which the dotfile and map files may set,
+// which is replaced when reloading
+type fileConfig struct {
+	error404FilePath string
+	headerMap        map[string][]string
+	headerMapEnabled bool
+	redirectRoutes   map[string]string
+	templateMap      map[string]string
+	vueJSHistoryMode bool
+	dotfileLoaded    bool
+	loadedFiles      map[string]bool
+	sources          map[string]Source
+}
+
+// snapshotFileConfig returns the current configuration which files may set
+func (w *WebServer) snapshotFileConfig() fileConfig {
+	sources := map[string]Source{}
+	for k, v := range w.configSources {
+		sources[k] = v
+	}
+	return fileConfig{
+		error404FilePath: w.Error404FilePath,
+		headerMap:        w.HeaderMap,
+		headerMapEnabled: w.HeaderMapEnabled,
+		redirectRoutes:   w.RedirectRoutes,
+		templateMap:      w.TemplateMap,
+		vueJSHistoryMode: w.VueJSHistoryMode,
+		dotfileLoaded:    w.dotfileLoaded,
+		loadedFiles:      w.loadedFiles,
+		sources:          sources,
+	}
+}
+
+// restoreFileConfig sets the configuration which files may set from a snapshot
+func (w *WebServer) restoreFileConfig(c fileConfig) {
+	w.Error404FilePath = c.error404FilePath
+	w.HeaderMap = c.headerMap
+	w.HeaderMapEnabled = c.headerMapEnabled
+	w.RedirectRoutes = c.redirectRoutes
+	w.TemplateMap = c.templateMap
+	w.VueJSHistoryMode = c.vueJSHistoryMode
+	w.dotfileLoaded = c.dotfileLoaded
+	w.loadedFiles = c.loadedFiles
+	w.configSources = map[string]Source{}
+	for k, v := range c.sources {
+		w.configSources[k] = v
+	}
+}
+
+// Reload reloads the dotfile, header map, template map and redirect routes from their files.
+// The new configuration is validated and its routing swapped in for new requests, whilst
+// in-flight requests finish with the current routing. When invalid, the current configuration is kept
+func (w *WebServer) Reload() error {
+	w.handlerMu.Lock()
+	defer w.handlerMu.Unlock()
+	err := w.reload()
+	metrics.RecordConfigReload(err)
+	if err != nil {
+		slog.Error("failed to reload config, keeping the current config", "error", err)
+		return err
+	}
+	slog.Info("reloaded config")
+	return nil
+}
+
+func (w *WebServer) reload() error {
+	w.loadConfig()
+	current := w.snapshotFileConfig()
+	currentHandler := w.handler
+	// as when starting, missing map files are left unloaded,
+	// though a map file which was loaded must still be there
+	allowMissing := map[string]bool{}
+	for _, f := range w.configFiles(*w.fileBase) {
+		allowMissing[f.path] = !w.loadedFiles[f.path]
+	}
+	if errs := w.validateFiles(*w.fileBase, allowMissing); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	w.restoreFileConfig(*w.fileBase)
+	if !w.handlerSet {
+		// the current handler is still serving, so load into a new one
+		w.handler = w.newHandlerForWebServer()
+	}
+	w.configLoaded = false
+	w.loadConfig()
+	if err := w.indexTemplateErr(); err != nil {
+		w.restoreFileConfig(current)
+		w.handler = currentHandler
+		return err
+	}
+	h := w.assembleHandler()
+	w.httpHandler.Store(&h)
+	return nil
+}
+
+// markFileLoaded records that the current configuration was loaded from the map file at path,
+// for reloads to require it. The set is replaced rather than changed, as snapshots share it
+func (w *WebServer) markFileLoaded(path string) {
+	files := map[string]bool{path: true}
+	for k, v := range w.loadedFiles {
+		files[k] = v
+	}
+	w.loadedFiles = files
+}
+
+// reloadOnSignal reloads on SIGHUP, until the context is cancelled
+func (w *WebServer) reloadOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("received SIGHUP, reloading config")
+			_ = w.Reload()
+			if w.certStore != nil {
+				if err := w.certStore.Reload(); err != nil {
+					slog.Error("failed to reload TLS certificates, keeping the current certificates", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// watchConfig reloads when a config file changes from the hashes loaded, checking every
+// reload interval until the context is cancelled. Files are compared by content, so that
+// the symlinks swapped when a Kubernetes ConfigMap is updated are followed
+func (w *WebServer) watchConfig(ctx context.Context, last map[string][sha256.Size]byte) {
+	if w.ReloadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := w.hashConfigFiles()
+		if reflect.DeepEqual(current, last) {
+			continue
+		}
+		last = current
+		slog.Info("config files changed, reloading config")
+		_ = w.Reload()
+	}
+}
+
+// hashConfigFiles returns the hash of the content of each config file, by path.
+// Files which don't exist have an empty hash
+func (w *WebServer) hashConfigFiles() map[string][sha256.Size]byte {
+	w.handlerMu.Lock()
+	files := w.configFiles(*w.fileBase)
+	w.handlerMu.Unlock()
+
+	hashes := map[string][sha256.Size]byte{}
+	for _, f := range files {
+		content, err := os.ReadFile(f.path)
+		if err != nil {
+			hashes[f.path] = [sha256.Size]byte{}
+			continue
+		}
+		hashes[f.path] = sha256.Sum256(content)
+	}
+	return hashes
+}
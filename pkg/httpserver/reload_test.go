@@ -0,0 +1,180 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestWebServer_Reload(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		newFiles   map[string]string
+		path       string
+		wantErr    bool
+		wantCode   int
+		wantHeader string
+	}{
+		{
+			name:       "header map changed",
+			files:      map[string]string{"headers.yaml": "X-Abc: [a]\n"},
+			newFiles:   map[string]string{"headers.yaml": "X-Abc: [b]\n"},
+			path:       "/",
+			wantCode:   http.StatusOK,
+			wantHeader: "b",
+		},
+		{
+			name:       "invalid header map keeps the current config",
+			files:      map[string]string{"headers.yaml": "X-Abc: [a]\n"},
+			newFiles:   map[string]string{"headers.yaml": "X-Abc: [a\n"},
+			path:       "/",
+			wantErr:    true,
+			wantCode:   http.StatusOK,
+			wantHeader: "a",
+		},
+		{
+			name:       "removed header map keeps the current config",
+			files:      map[string]string{"headers.yaml": "X-Abc: [a]\n"},
+			newFiles:   map[string]string{"headers.yaml": ""},
+			path:       "/",
+			wantErr:    true,
+			wantCode:   http.StatusOK,
+			wantHeader: "a",
+		},
+		{
+			name:     "redirect routes changed",
+			files:    map[string]string{"redirects.yaml": "/a: /b\n"},
+			newFiles: map[string]string{"redirects.yaml": "/c: /d\n"},
+			path:     "/c",
+			wantCode: http.StatusTemporaryRedirect,
+		},
+		{
+			name:     "removed redirect routes keeps the current config",
+			files:    map[string]string{"redirects.yaml": "/a: /b\n"},
+			newFiles: map[string]string{"redirects.yaml": ""},
+			path:     "/a",
+			wantErr:  true,
+			wantCode: http.StatusTemporaryRedirect,
+		},
+		{
+			name:     "dotfile added",
+			newFiles: map[string]string{"site/.ghs.yaml": "historyMode: true\n"},
+			path:     "/a/b/c",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "dotfile removed reverts to the server config",
+			files:    map[string]string{"site/.ghs.yaml": "historyMode: true\n"},
+			newFiles: map[string]string{"site/.ghs.yaml": ""},
+			path:     "/a/b/c",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "invalid dotfile keeps the current config",
+			files:    map[string]string{"site/.ghs.yaml": "historyMode: true\n"},
+			newFiles: map[string]string{"site/.ghs.yaml": "historyMode: maybe\n"},
+			path:     "/a/b/c",
+			wantErr:  true,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"site/index.html": "hello", "site/404.html": "not found"})
+			writeFiles(t, dir, tt.files)
+			ws := New(
+				WithServeFolder(path.Join(dir, "site")),
+				WithHeaderMapPath(path.Join(dir, "headers.yaml")),
+				WithRedirectRoutesPath(path.Join(dir, "redirects.yaml")),
+			)
+			ws.Handler()
+			writeFiles(t, dir, tt.newFiles)
+			if err := ws.Reload(); (err != nil) != tt.wantErr {
+				t.Fatalf("WebServer.Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			rec := httptest.NewRecorder()
+			ws.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("WebServer.Reload() then %v = %v, want %v", tt.path, rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("X-Abc"); got != tt.wantHeader {
+				t.Errorf("WebServer.Reload() then header X-Abc = %v, want %v", got, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestWebServer_watchConfig(t *testing.T) {
+	// ConfigMaps are mounted as symlinks to a data folder, which is swapped on update
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"site/index.html":     "hello",
+		"data-1/headers.yaml": "X-Abc: [a]\n",
+		"data-2/headers.yaml": "X-Abc: [b]\n",
+	})
+	if err := os.MkdirAll(path.Join(dir, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(path.Join(dir, "data-1"), path.Join(dir, "config", "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(path.Join("..data", "headers.yaml"), path.Join(dir, "config", "headers.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	ws := New(
+		WithServeFolder(path.Join(dir, "site")),
+		WithHeaderMapPath(path.Join(dir, "config", "headers.yaml")),
+		WithReloadInterval(10*time.Millisecond),
+	)
+	ws.Handler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ws.watchConfig(ctx, ws.hashConfigFiles())
+
+	if err := os.Symlink(path.Join(dir, "data-2"), path.Join(dir, "config", "..data-new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path.Join(dir, "config", "..data-new"), path.Join(dir, "config", "..data")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := httptest.NewRecorder()
+		ws.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Header().Get("X-Abc") == "b" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("WebServer.watchConfig() header X-Abc = %v, want b", rec.Header().Get("X-Abc"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// writeFiles writes files by path in the dir, removing files with no content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := path.Join(dir, name)
+		if content == "" {
+			if err := os.Remove(p); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// Route is an entry of the routing table
+type Route struct {
+	Path    string
+	Methods []string
+	Mode    string
+	Target  string
+}
+
+// Routes returns the effective routing table, in the order requests are matched:
+// redirects, then extra handlers, then the fallback serving the folder
+func (w *WebServer) Routes() []Route {
+	w.handlerMu.Lock()
+	defer w.handlerMu.Unlock()
+	w.loadConfig()
+
+	routes := []Route{}
+	if w.RedirectRoutesEnabled {
+		redirects := []Route{}
+		for from, to := range w.RedirectRoutes {
+			redirects = append(redirects, Route{Path: from, Methods: []string{http.MethodGet}, Mode: "redirect", Target: to})
+		}
+		sort.Slice(redirects, func(i, j int) bool {
+			return redirects[i].Path < redirects[j].Path
+		})
+		routes = append(routes, redirects...)
+	}
+	for _, h := range w.ExtraHandlers {
+		if h.Path == "/" {
+			continue
+		}
+		methods := h.HTTPMethods
+		if len(methods) == 0 {
+			methods = []string{"*"}
+		}
+		routes = append(routes, Route{Path: h.Path, Methods: methods, Mode: "extra handler", Target: runtime.FuncForPC(reflect.ValueOf(h.HandlerFunc).Pointer()).Name()})
+	}
+
+	fullServePath, _ := filepath.Abs(w.ServeFolder)
+	fallback := Route{Path: "/*", Methods: []string{"*"}, Mode: "static", Target: fullServePath}
+	if w.VueJSHistoryMode {
+		fallback.Mode = "history"
+		fallback.Target = filepath.Join(fullServePath, "index.html")
+	} else if w.Error404FilePath != "" {
+		fallback.Target += fmt.Sprintf(" (404: %v)", w.Error404FilePath)
+	}
+	return append(routes, fallback)
+}
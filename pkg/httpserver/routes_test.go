@@ -0,0 +1,52 @@
+package httpserver
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func handleExample(w http.ResponseWriter, r *http.Request) {}
+
+func TestWebServer_Routes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []Route
+	}{
+		{
+			name: "static",
+			opts: []Option{WithServeFolder("/srv"), WithError404FilePath("404.html")},
+			want: []Route{
+				{Path: "/*", Methods: []string{"*"}, Mode: "static", Target: "/srv (404: 404.html)"},
+			},
+		},
+		{
+			name: "redirects and extra handlers with history mode",
+			opts: []Option{
+				WithServeFolder("/srv"),
+				WithVueJSHistoryMode(true),
+				WithRedirectRoutes(map[string]string{"/b": "https://example.com", "/a": "/c"}),
+				WithExtraHandlers(
+					&ExtraHandler{Path: "/api", HandlerFunc: handleExample, HTTPMethods: []string{http.MethodPost}},
+					&ExtraHandler{Path: "/", HandlerFunc: handleExample},
+				),
+			},
+			want: []Route{
+				{Path: "/a", Methods: []string{http.MethodGet}, Mode: "redirect", Target: "/c"},
+				{Path: "/b", Methods: []string{http.MethodGet}, Mode: "redirect", Target: "https://example.com"},
+				{Path: "/api", Methods: []string{http.MethodPost}, Mode: "extra handler", Target: "gitlab.com/BobyMCbobs/go-http-server/pkg/httpserver.handleExample"},
+				{Path: "/*", Methods: []string{"*"}, Mode: "history", Target: "/srv/index.html"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := New(tt.opts...).Routes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WebServer.Routes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/certs"
+)
+
+// TLSCertificate is the paths of a certificate and key to serve HTTPS with, chosen by server name
+type TLSCertificate struct {
+	CertPath string `json:"certPath"`
+	KeyPath  string `json:"keyPath"`
+}
+
+// client certificate authentication modes, as tls.ClientAuthType
+const (
+	ClientAuthNone             = "none"
+	ClientAuthRequest          = "request"
+	ClientAuthRequireAny       = "require-any"
+	ClientAuthVerifyIfGiven    = "verify-if-given"
+	ClientAuthRequireAndVerify = "require-and-verify"
+)
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                         tls.NoClientCert,
+	ClientAuthNone:             tls.NoClientCert,
+	ClientAuthRequest:          tls.RequestClientCert,
+	ClientAuthRequireAny:       tls.RequireAnyClientCert,
+	ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+	ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+}
+
+// headers set to the identity of the verified client certificate
+const (
+	HeaderClientCertSubject = "X-Client-Cert-Subject"
+	HeaderClientCertSAN     = "X-Client-Cert-SAN"
+)
+
+// TLSClientAuthRule sets whether requests with paths under the path, by whole segments, require a verified client certificate
+type TLSClientAuthRule struct {
+	Path     string `json:"path"`
+	Required bool   `json:"required"`
+}
+
+// clientAuthType returns the client certificate authentication of handshakes. With rules,
+// certificates are verified when given, for the rules to be checked on each request
+func (w *WebServer) clientAuthType() tls.ClientAuthType {
+	t := clientAuthTypes[w.TLSClientAuth]
+	if t == tls.NoClientCert && len(w.TLSClientAuthRules) > 0 {
+		return tls.VerifyClientCertIfGiven
+	}
+	return t
+}
+
+// configureClientAuth sets the client certificate authentication of the TLS config, loading
+// the client CA bundles. ACME TLS-ALPN-01 challenges are answered without client certificates.
+// The config is left as it is when client certificate authentication isn't configured
+func (w *WebServer) configureClientAuth(cfg *tls.Config) error {
+	clientAuth := w.clientAuthType()
+	if clientAuth == tls.NoClientCert {
+		return nil
+	}
+	cfg.ClientAuth = clientAuth
+	getConfigForClient := cfg.GetConfigForClient
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if !slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+			if getConfigForClient != nil {
+				return getConfigForClient(hello)
+			}
+			return nil, nil
+		}
+		challenge := cfg.Clone()
+		challenge.ClientAuth = tls.NoClientCert
+		challenge.GetConfigForClient = nil
+		return challenge, nil
+	}
+	if len(w.TLSClientCAPaths) == 0 {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	for _, p := range w.TLSClientCAPaths {
+		bundle, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return fmt.Errorf("no certificates found in client CA bundle %v", p)
+		}
+	}
+	cfg.ClientCAs = pool
+	return nil
+}
+
+// clientCertMiddleware requires a verified client certificate for the paths which require one,
+// and sets headers to the identity of the verified client certificate, in place of any sent by the client
+func (w *WebServer) clientCertMiddleware(next http.Handler) http.Handler {
+	if w.clientAuthType() == tls.NoClientCert {
+		return next
+	}
+	rules := append([]TLSClientAuthRule{}, w.TLSClientAuthRules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Path) > len(rules[j].Path)
+	})
+	requiredByDefault := w.TLSClientAuth == ClientAuthRequireAndVerify
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		r.Header.Del(HeaderClientCertSubject)
+		r.Header.Del(HeaderClientCertSAN)
+		subject, sans, ok := certs.ClientIdentity(r.TLS)
+		if ok {
+			r.Header.Set(HeaderClientCertSubject, subject)
+			if len(sans) > 0 {
+				r.Header.Set(HeaderClientCertSAN, strings.Join(sans, ","))
+			}
+			next.ServeHTTP(rw, r)
+			return
+		}
+		required := requiredByDefault
+		for _, rule := range rules {
+			if hasPathPrefix(r.URL.Path, rule.Path) {
+				required = rule.Required
+				break
+			}
+		}
+		if required {
+			http.Error(rw, "a verified client certificate is required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// validateTLS ensures that the TLS certificates and client certificate authentication are valid
+func (w *WebServer) validateTLS() error {
+	var errs []error
+	for i, c := range w.TLSCertificates {
+		if c.CertPath == "" || c.KeyPath == "" {
+			errs = append(errs, fmt.Errorf("TLS certificate %v requires both a cert path and key path", i))
+		}
+	}
+	if _, ok := clientAuthTypes[w.TLSClientAuth]; !ok {
+		errs = append(errs, fmt.Errorf("invalid TLS client auth '%v', expected one of %v, %v, %v, %v or %v", w.TLSClientAuth,
+			ClientAuthNone, ClientAuthRequest, ClientAuthRequireAny, ClientAuthVerifyIfGiven, ClientAuthRequireAndVerify))
+		return errors.Join(errs...)
+	}
+	if len(w.TLSClientAuthRules) > 0 {
+		switch w.TLSClientAuth {
+		case ClientAuthRequest, ClientAuthRequireAny:
+			errs = append(errs, fmt.Errorf("TLS client auth rules require client certificates to be verified, not '%v'", w.TLSClientAuth))
+		}
+	}
+	for _, rule := range w.TLSClientAuthRules {
+		if !strings.HasPrefix(rule.Path, "/") {
+			errs = append(errs, fmt.Errorf("invalid TLS client auth rule path '%v', expected a path starting with /", rule.Path))
+		}
+	}
+	switch w.clientAuthType() {
+	case tls.VerifyClientCertIfGiven, tls.RequireAndVerifyClientCert:
+		if len(w.TLSClientCAPaths) == 0 {
+			errs = append(errs, errors.New("verifying client certificates requires client CA bundles"))
+		}
+	}
+	return errors.Join(errs...)
+}
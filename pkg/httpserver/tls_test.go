@@ -0,0 +1,323 @@
+package httpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and key for the DNS names
+func writeTestCertificate(t *testing.T, certPath string, keyPath string, dnsNames ...string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// listenHTTPS serves the WebServer's HTTPS listener, returning its address
+func listenHTTPS(t *testing.T, ws *WebServer) string {
+	t.Helper()
+	ws.accessLogOut = io.Discard
+	ws.build()
+	servers, err := ws.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range servers {
+		s := s
+		go func() { _ = s.server.Serve(s.listener) }()
+	}
+	t.Cleanup(func() {
+		ws.server.Close()
+		ws.serverTLS.Close()
+	})
+	for _, s := range servers {
+		if s.name == "https" {
+			return s.listener.Addr().String()
+		}
+	}
+	t.Fatal("no HTTPS listener")
+	return ""
+}
+
+func TestWebServer_listen_sni(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCertificate(t, path.Join(dir, "default.crt"), path.Join(dir, "default.key"), "default.example.com")
+	certDir := path.Join(dir, "certs")
+	if err := os.Mkdir(certDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestCertificate(t, path.Join(certDir, "a.crt"), path.Join(certDir, "a.key"), "a.example.com")
+	writeTestCertificate(t, path.Join(certDir, "wildcard.crt"), path.Join(certDir, "wildcard.key"), "*.example.org")
+	defaultCertificate := TLSCertificate{CertPath: path.Join(dir, "default.crt"), KeyPath: path.Join(dir, "default.key")}
+
+	tests := []struct {
+		name          string
+		serverName    string
+		rejectUnknown bool
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:       "by name",
+			serverName: "a.example.com",
+			want:       "a.example.com",
+		},
+		{
+			name:       "by wildcard",
+			serverName: "www.example.org",
+			want:       "*.example.org",
+		},
+		{
+			name:       "unknown served the default",
+			serverName: "b.example.com",
+			want:       "default.example.com",
+		},
+		{
+			name:          "unknown rejected",
+			serverName:    "b.example.com",
+			rejectUnknown: true,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ws := New(
+				WithServeFolder(t.TempDir()),
+				WithAppPort("127.0.0.1:0"),
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("127.0.0.1:0")}),
+				WithTLSCertificates(certDir, tt.rejectUnknown, defaultCertificate),
+			)
+			addr := listenHTTPS(t, ws)
+			conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: tt.serverName, InsecureSkipVerify: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TLS handshake for %v error = %v, wantErr %v", tt.serverName, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer conn.Close()
+			if got := conn.ConnectionState().PeerCertificates[0].Subject.CommonName; got != tt.want {
+				t.Errorf("TLS handshake for %v certificate = %v, want %v", tt.serverName, got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestCA writes a self-signed CA certificate, returning it and its key to sign client certificates with
+func writeTestCA(t *testing.T, certPath string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, key
+}
+
+// testClientCertificate returns a client certificate for the name, signed by the CA
+func testClientCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestWebServer_listen_clientAuth(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCertificate(t, path.Join(dir, "server.crt"), path.Join(dir, "server.key"), "localhost")
+	ca, caKey := writeTestCA(t, path.Join(dir, "ca.crt"))
+	client := testClientCertificate(t, ca, caKey, "dashboard.example.com")
+	otherCA, otherCAKey := writeTestCA(t, path.Join(dir, "other-ca.crt"))
+	untrusted := testClientCertificate(t, otherCA, otherCAKey, "untrusted.example.com")
+
+	serveFolder := path.Join(dir, "site")
+	if err := os.MkdirAll(path.Join(serveFolder, "admin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"index.html", "admin/index.html", "administrator.html"} {
+		if err := os.WriteFile(path.Join(serveFolder, p), []byte("ok"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		certificate *tls.Certificate
+		forged      bool
+		wantStatus  int
+		wantSubject string
+		wantSAN     string
+	}{
+		{
+			name:        "required path with certificate",
+			path:        "/admin/",
+			certificate: &client,
+			wantStatus:  http.StatusOK,
+			wantSubject: "CN=dashboard.example.com",
+			wantSAN:     "dashboard.example.com",
+		},
+		{
+			name:       "required path without certificate",
+			path:       "/admin/",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "required path with forged headers",
+			path:       "/admin/",
+			forged:     true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "path sharing the prefix of a required path without certificate",
+			path:       "/administrator.html",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "public path without certificate",
+			path:       "/",
+			forged:     true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "public path with certificate",
+			path:        "/",
+			certificate: &client,
+			wantStatus:  http.StatusOK,
+			wantSubject: "CN=dashboard.example.com",
+			wantSAN:     "dashboard.example.com",
+		},
+		{
+			name:        "untrusted certificate",
+			path:        "/",
+			certificate: &untrusted,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ws := New(
+				WithServeFolder(serveFolder),
+				WithAppPort("127.0.0.1:0"),
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("127.0.0.1:0")}),
+				WithTLSCertificates("", false, TLSCertificate{CertPath: path.Join(dir, "server.crt"), KeyPath: path.Join(dir, "server.key")}),
+				WithTLSClientAuth(ClientAuthVerifyIfGiven, []string{path.Join(dir, "ca.crt")}, TLSClientAuthRule{Path: "/admin", Required: true}),
+			)
+			ws.SetExtraMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+					rw.Header().Set("Got-Subject", r.Header.Get(HeaderClientCertSubject))
+					rw.Header().Set("Got-SAN", r.Header.Get(HeaderClientCertSAN))
+					next.ServeHTTP(rw, r)
+				})
+			})
+			addr := listenHTTPS(t, ws)
+			tlsConfig := &tls.Config{InsecureSkipVerify: true}
+			if tt.certificate != nil {
+				tlsConfig.Certificates = []tls.Certificate{*tt.certificate}
+			}
+			c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			req, err := http.NewRequest(http.MethodGet, "https://"+addr+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.forged {
+				req.Header.Set(HeaderClientCertSubject, "CN=forged")
+				req.Header.Set(HeaderClientCertSAN, "forged.example.com")
+			}
+			resp, err := c.Do(req)
+			if tt.wantStatus == 0 {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("GET %v with an untrusted certificate succeeded, want a failed handshake", tt.path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %v status = %v, want %v", tt.path, resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := resp.Header.Get("Got-Subject"); got != tt.wantSubject {
+				t.Errorf("GET %v client cert subject = %v, want %v", tt.path, got, tt.wantSubject)
+			}
+			if got := resp.Header.Get("Got-SAN"); got != tt.wantSAN {
+				t.Errorf("GET %v client cert SAN = %v, want %v", tt.path, got, tt.wantSAN)
+			}
+		})
+	}
+}
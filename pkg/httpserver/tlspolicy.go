@@ -0,0 +1,205 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/metrics"
+)
+
+// TLS policy presets, following the Mozilla server side TLS recommendations
+const (
+	TLSPresetModern       = "modern"
+	TLSPresetIntermediate = "intermediate"
+)
+
+// TLSPolicy is the protocol versions, cipher suites, curves, session tickets and ALPN protocols of HTTPS.
+// Unset fields use the preset, otherwise the Go defaults
+type TLSPolicy struct {
+	Preset                 string
+	MinVersion             string
+	MaxVersion             string
+	CipherSuites           []string
+	Curves                 []string
+	SessionTicketsDisabled bool
+	ALPN                   []string
+}
+
+var tlsPresets = map[string]TLSPolicy{
+	TLSPresetModern: {
+		MinVersion: "1.3",
+		Curves:     []string{"X25519", "P-256", "P-384"},
+	},
+	TLSPresetIntermediate: {
+		MinVersion: "1.2",
+		CipherSuites: []string{
+			"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+			"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+			"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+			"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		},
+		Curves: []string{"X25519", "P-256", "P-384"},
+	},
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P-256":  tls.CurveP256,
+	"P-384":  tls.CurveP384,
+	"P-521":  tls.CurveP521,
+}
+
+// the ALPN protocols served over HTTPS
+var tlsALPNProtocols = []string{"h2", "http/1.1"}
+
+// tlsPolicy returns the TLS policy, with the preset for unset fields
+func (w *WebServer) tlsPolicy() TLSPolicy {
+	p := tlsPresets[w.TLSPreset]
+	p.Preset = w.TLSPreset
+	if w.TLSMinVersion != "" {
+		p.MinVersion = w.TLSMinVersion
+	}
+	if w.TLSMaxVersion != "" {
+		p.MaxVersion = w.TLSMaxVersion
+	}
+	if len(w.TLSCipherSuites) > 0 {
+		p.CipherSuites = w.TLSCipherSuites
+	}
+	if len(w.TLSCurves) > 0 {
+		p.Curves = w.TLSCurves
+	}
+	p.SessionTicketsDisabled = w.TLSSessionTicketsDisabled
+	p.ALPN = w.TLSALPN
+	return p
+}
+
+// apply sets the policy on the TLS config, keeping the config's own values where the policy is unset.
+// ACME TLS-ALPN-01 challenges stay answered when the ALPN protocols are set
+func (p TLSPolicy) apply(cfg *tls.Config) error {
+	var errs []error
+	if p.Preset != "" {
+		if _, ok := tlsPresets[p.Preset]; !ok {
+			errs = append(errs, fmt.Errorf("invalid TLS preset '%v', expected %v or %v", p.Preset, TLSPresetModern, TLSPresetIntermediate))
+		}
+	}
+	var err error
+	if p.MinVersion != "" {
+		if cfg.MinVersion, err = parseTLSVersion(p.MinVersion); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.MaxVersion != "" {
+		if cfg.MaxVersion, err = parseTLSVersion(p.MaxVersion); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.MinVersion != 0 && cfg.MaxVersion != 0 && cfg.MinVersion > cfg.MaxVersion {
+		errs = append(errs, fmt.Errorf("the minimum TLS version %v is above the maximum TLS version %v", p.MinVersion, p.MaxVersion))
+	}
+	if len(p.CipherSuites) > 0 {
+		if cfg.CipherSuites, err = parseCipherSuites(p.CipherSuites); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(p.Curves) > 0 {
+		cfg.CurvePreferences = nil
+	}
+	for _, name := range p.Curves {
+		curve, ok := tlsCurves[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid TLS curve '%v', expected one of X25519, P-256, P-384 or P-521", name))
+			continue
+		}
+		cfg.CurvePreferences = append(cfg.CurvePreferences, curve)
+	}
+	if p.SessionTicketsDisabled {
+		cfg.SessionTicketsDisabled = true
+	}
+	if len(p.ALPN) > 0 {
+		for _, proto := range p.ALPN {
+			if !slices.Contains(tlsALPNProtocols, proto) {
+				errs = append(errs, fmt.Errorf("invalid ALPN protocol '%v', expected %v", proto, strings.Join(tlsALPNProtocols, " or ")))
+			}
+		}
+		if slices.Contains(p.ALPN, "h2") && len(cfg.CipherSuites) > 0 &&
+			!slices.Contains(cfg.CipherSuites, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) &&
+			!slices.Contains(cfg.CipherSuites, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256) {
+			errs = append(errs, errors.New("HTTP/2 requires the TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 or TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 cipher suite"))
+		}
+		protos := slices.Clone(p.ALPN)
+		if slices.Contains(cfg.NextProtos, acme.ALPNProto) {
+			protos = append(protos, acme.ALPNProto)
+		}
+		cfg.NextProtos = protos
+	}
+	return errors.Join(errs...)
+}
+
+// parseTLSVersion returns the TLS version of a name such as 1.2, or zero for the default when empty
+func parseTLSVersion(name string) (uint16, error) {
+	if name == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version '%v', expected one of 1.0, 1.1, 1.2 or 1.3", name)
+	}
+	return v, nil
+}
+
+// parseCipherSuites returns the IDs of the named cipher suites, which must be secure and configurable
+func parseCipherSuites(names []string) ([]uint16, error) {
+	var ids []uint16
+	var errs []error
+	for _, name := range names {
+		i := slices.IndexFunc(tls.CipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name })
+		if i == -1 {
+			if slices.ContainsFunc(tls.InsecureCipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name }) {
+				errs = append(errs, fmt.Errorf("cipher suite %v is insecure", name))
+				continue
+			}
+			errs = append(errs, fmt.Errorf("unknown cipher suite '%v'", name))
+			continue
+		}
+		suite := tls.CipherSuites()[i]
+		if !slices.ContainsFunc(suite.SupportedVersions, func(v uint16) bool { return v < tls.VersionTLS13 }) {
+			errs = append(errs, fmt.Errorf("cipher suite %v is only used by TLS 1.3, and can't be set", name))
+			continue
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, errors.Join(errs...)
+}
+
+// recordHandshakes counts each TLS handshake of the config by the negotiated version and cipher suite
+func recordHandshakes(cfg *tls.Config) {
+	verify := cfg.VerifyConnection
+	cfg.VerifyConnection = func(state tls.ConnectionState) error {
+		if verify != nil {
+			if err := verify(state); err != nil {
+				return err
+			}
+		}
+		metrics.RecordTLSHandshake(state.Version, state.CipherSuite)
+		return nil
+	}
+}
+
+// validateTLSPolicy ensures that the TLS policy is valid
+func (w *WebServer) validateTLSPolicy() error {
+	return w.tlsPolicy().apply(&tls.Config{})
+}
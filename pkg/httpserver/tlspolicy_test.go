@@ -0,0 +1,149 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"path"
+	"testing"
+)
+
+func TestWebServer_listen_tlsPolicy(t *testing.T) {
+	dir := t.TempDir()
+	certificate := TLSCertificate{CertPath: path.Join(dir, "tls.crt"), KeyPath: path.Join(dir, "tls.key")}
+	writeTestCertificate(t, certificate.CertPath, certificate.KeyPath, "localhost")
+
+	tests := []struct {
+		name        string
+		policy      TLSPolicy
+		client      *tls.Config
+		wantErr     bool
+		wantVersion uint16
+		wantCipher  uint16
+		wantProto   string
+	}{
+		{
+			name:        "modern with TLS 1.3",
+			policy:      TLSPolicy{Preset: TLSPresetModern},
+			client:      &tls.Config{},
+			wantVersion: tls.VersionTLS13,
+		},
+		{
+			name:    "modern rejects TLS 1.2",
+			policy:  TLSPolicy{Preset: TLSPresetModern},
+			client:  &tls.Config{MaxVersion: tls.VersionTLS12},
+			wantErr: true,
+		},
+		{
+			name:        "intermediate with TLS 1.2",
+			policy:      TLSPolicy{Preset: TLSPresetIntermediate},
+			client:      &tls.Config{MaxVersion: tls.VersionTLS12},
+			wantVersion: tls.VersionTLS12,
+		},
+		{
+			name:    "intermediate rejects a cipher suite outside the preset",
+			policy:  TLSPolicy{Preset: TLSPresetIntermediate},
+			client:  &tls.Config{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA}},
+			wantErr: true,
+		},
+		{
+			name:        "cipher suites",
+			policy:      TLSPolicy{CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"}},
+			client:      &tls.Config{MaxVersion: tls.VersionTLS12},
+			wantVersion: tls.VersionTLS12,
+			wantCipher:  tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		{
+			name:    "maximum version",
+			policy:  TLSPolicy{MaxVersion: "1.2"},
+			client:  &tls.Config{MinVersion: tls.VersionTLS13},
+			wantErr: true,
+		},
+		{
+			name:        "ALPN",
+			policy:      TLSPolicy{ALPN: []string{"h2", "http/1.1"}},
+			client:      &tls.Config{NextProtos: []string{"h2", "http/1.1"}},
+			wantVersion: tls.VersionTLS13,
+			wantProto:   "h2",
+		},
+		{
+			name:        "no ALPN by default",
+			client:      &tls.Config{NextProtos: []string{"h2", "http/1.1"}},
+			wantVersion: tls.VersionTLS13,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ws := New(
+				WithServeFolder(t.TempDir()),
+				WithAppPort("127.0.0.1:0"),
+				WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("127.0.0.1:0")}),
+				WithTLSCertificates("", false, certificate),
+				WithTLSPolicy(tt.policy),
+			)
+			if errs := ws.Validate(); len(errs) > 0 {
+				t.Fatalf("invalid config: %v", errs)
+			}
+			addr := listenHTTPS(t, ws)
+			tt.client.InsecureSkipVerify = true
+			conn, err := tls.Dial("tcp", addr, tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TLS handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer conn.Close()
+			state := conn.ConnectionState()
+			if state.Version != tt.wantVersion {
+				t.Errorf("TLS version = %v, want %v", tls.VersionName(state.Version), tls.VersionName(tt.wantVersion))
+			}
+			if tt.wantCipher != 0 && state.CipherSuite != tt.wantCipher {
+				t.Errorf("cipher suite = %v, want %v", tls.CipherSuiteName(state.CipherSuite), tls.CipherSuiteName(tt.wantCipher))
+			}
+			if state.NegotiatedProtocol != tt.wantProto {
+				t.Errorf("ALPN protocol = %v, want %v", state.NegotiatedProtocol, tt.wantProto)
+			}
+		})
+	}
+}
+
+func TestWebServer_LoadTLS_withTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+	writeTestCertificate(t, certPath, keyPath, "localhost")
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	ws := New(
+		WithServeFolder(t.TempDir()),
+		WithConfig(SourceOption, &Config{HTTPSPortEnabled: pointer(true), HTTPSPort: pointer("127.0.0.1:0")}),
+		WithTLSConfig(cfg),
+		WithTLSPolicy(TLSPolicy{MaxVersion: "1.2"}),
+		WithTLSClientAuth("request", nil),
+	)
+	if _, err := ws.LoadTLS(); err != nil {
+		t.Fatalf("WebServer.LoadTLS() error = %v", err)
+	}
+	got := ws.TLSConfig
+	if got == cfg {
+		t.Fatalf("WebServer.LoadTLS() serves the given config, want a copy")
+	}
+	if len(got.Certificates) != 1 || got.MinVersion != tls.VersionTLS12 {
+		t.Errorf("WebServer.LoadTLS() certificates = %v, min version = %v, want those of the given config", len(got.Certificates), tls.VersionName(got.MinVersion))
+	}
+	if got.MaxVersion != tls.VersionTLS12 {
+		t.Errorf("WebServer.LoadTLS() max version = %v, want the policy's TLS 1.2", tls.VersionName(got.MaxVersion))
+	}
+	if got.ClientAuth != tls.RequestClientCert {
+		t.Errorf("WebServer.LoadTLS() client auth = %v, want %v", got.ClientAuth, tls.RequestClientCert)
+	}
+	if got.VerifyConnection == nil {
+		t.Errorf("WebServer.LoadTLS() doesn't record handshakes")
+	}
+	if cfg.MaxVersion != 0 || cfg.ClientAuth != tls.NoClientCert || cfg.VerifyConnection != nil {
+		t.Errorf("WebServer.LoadTLS() changed the given config")
+	}
+}
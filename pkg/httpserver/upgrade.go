@@ -0,0 +1,222 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// environment variables which pass listeners to an upgraded process
+const (
+	// upgradeListenersEnv lists the inherited listeners as name:fd, separated by commas
+	upgradeListenersEnv = "APP_UPGRADE_LISTENERS"
+	// upgradeReadyFDEnv is the fd of a pipe to write to once the upgraded process is serving
+	upgradeReadyFDEnv = "APP_UPGRADE_READY_FD"
+)
+
+// upgradeReadyTimeout is the time given to an upgraded process to start serving
+const upgradeReadyTimeout = time.Minute
+
+// fileListener is a listener which has a file descriptor to pass to another process
+type fileListener interface {
+	net.Listener
+	File() (*os.File, error)
+}
+
+// inheritedListener returns the listener for the server passed by the process which
+// upgraded to this one, if any. Listeners are read from the environment once
+func (w *WebServer) inheritedListener(name string) (net.Listener, error) {
+	w.inheritOnce.Do(func() {
+		w.inheritedListeners, w.inheritErr = inheritListeners()
+	})
+	if w.inheritErr != nil {
+		return nil, w.inheritErr
+	}
+	l := w.inheritedListeners[name]
+	delete(w.inheritedListeners, name)
+	return l, nil
+}
+
+// inheritListeners returns the listeners passed by the process which upgraded to this one, by name
+func inheritListeners() (map[string]net.Listener, error) {
+	value := os.Getenv(upgradeListenersEnv)
+	os.Unsetenv(upgradeListenersEnv)
+	listeners := map[string]net.Listener{}
+	if value == "" {
+		return listeners, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		name, fdValue, ok := strings.Cut(item, ":")
+		fd, err := strconv.Atoi(fdValue)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid inherited listener '%v', expected name:fd", item)
+		}
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		if err != nil {
+			// UDP sockets, such as for HTTP/3, are passed as packet listeners
+			if pc, pcErr := net.FilePacketConn(f); pcErr == nil {
+				if conn, ok := pc.(*net.UDPConn); ok {
+					l, err = &packetListener{UDPConn: conn}, nil
+				} else {
+					pc.Close()
+				}
+			}
+		}
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to inherit listener for %v: %w", name, err)
+		}
+		listeners[name] = l
+	}
+	return listeners, nil
+}
+
+// notifyUpgradeReady tells the process which upgraded to this one that it is serving
+func notifyUpgradeReady() {
+	value := os.Getenv(upgradeReadyFDEnv)
+	os.Unsetenv(upgradeReadyFDEnv)
+	if value == "" {
+		return
+	}
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error("invalid upgrade ready fd", "fd", value)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "upgrade-ready")
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		slog.Error("failed to notify the previous process of being ready", "error", err)
+	}
+}
+
+// upgradeOnSignal upgrades to a new process on each upgrade signal, until an upgrade succeeds.
+// The new process is sent on upgraded once it is serving
+func (w *WebServer) upgradeOnSignal(ctx context.Context, upgraded chan<- *os.Process) {
+	if len(upgradeSignals) == 0 {
+		return
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, upgradeSignals...)
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			slog.Info("received signal, upgrading")
+			p, err := w.Upgrade(ctx)
+			if err != nil {
+				slog.Error("failed to upgrade, continuing to serve", "error", err)
+				continue
+			}
+			upgraded <- p
+			return
+		}
+	}
+}
+
+// Upgrade starts a new process from the executable, with the same arguments and environment,
+// passing the listeners to it and waiting for it to start serving.
+// Once this returns, the listeners are served by both processes and this one should be shut down
+func (w *WebServer) Upgrade(ctx context.Context) (*os.Process, error) {
+	w.mu.Lock()
+	servers := w.servers
+	w.mu.Unlock()
+
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	var names []string
+	for _, s := range servers {
+		fl, ok := s.raw.(fileListener)
+		if !ok {
+			return nil, fmt.Errorf("the %v listener can't be passed to another process", s.name)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the %v listener file: %w", s.name, err)
+		}
+		files = append(files, f)
+		// extra files are numbered from 3, after stdin, stdout and stderr
+		names = append(names, fmt.Sprintf("%v:%v", s.name, 2+len(files)))
+	}
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer ready.Close()
+	files = append(files, readyW)
+
+	args := w.upgradeArgs
+	if args == nil {
+		args = os.Args
+	}
+	executable, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the executable to upgrade to: %w", err)
+	}
+	cmd := exec.Command(executable, args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		upgradeListenersEnv+"="+strings.Join(names, ","),
+		fmt.Sprintf("%v=%v", upgradeReadyFDEnv, 2+len(files)),
+	)
+	err = cmd.Start()
+	for _, s := range servers {
+		if err := setNonblock(s.raw); err != nil {
+			slog.Error("failed to set the listener to non-blocking", "server", s.name, "error", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the upgraded process: %w", err)
+	}
+	// the child holds the only other copy of the pipe, for reads to end if it exits
+	readyW.Close()
+	go func() { _ = cmd.Wait() }()
+
+	readyErr := make(chan error, 1)
+	go func() {
+		_, err := ready.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = errors.New("the upgraded process exited before it was ready")
+		}
+		readyErr <- err
+	}()
+	select {
+	case err = <-readyErr:
+	case <-time.After(upgradeReadyTimeout):
+		err = fmt.Errorf("the upgraded process wasn't ready after %v", upgradeReadyTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return nil, err
+	}
+	for _, s := range servers {
+		// the socket file is now served by the upgraded process
+		if ul, ok := s.raw.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	w.upgraded.Store(true)
+	slog.Info("upgraded process is ready", "pid", cmd.Process.Pid)
+	return cmd.Process, nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package httpserver
+
+import (
+	"net"
+	"os"
+)
+
+// upgradeSignals are the signals to upgrade to a new process on, which has no equivalent here
+var upgradeSignals []os.Signal
+
+// setNonblock is only needed where listeners are passed to other processes
+func setNonblock(l net.Listener) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestWebServer_listen_inherited(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	t.Setenv(upgradeListenersEnv, fmt.Sprintf("http:%v", f.Fd()))
+
+	ws := New(WithServeFolder(t.TempDir()), WithAppPort("127.0.0.1:0"))
+	ws.accessLogOut = io.Discard
+	ws.build()
+	servers, err := ws.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer servers[0].listener.Close()
+	if got, want := servers[0].listener.Addr().String(), l.Addr().String(); got != want {
+		t.Errorf("WebServer.listen() addr = %v, want the inherited %v", got, want)
+	}
+}
+
+func TestWebServer_Upgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr bool
+	}{
+		{
+			name:   "ready",
+			script: `case "$` + upgradeListenersEnv + `" in http:3) printf x >&"$` + upgradeReadyFDEnv + `";; esac`,
+		},
+		{
+			name:    "exits before ready",
+			script:  `exit 1`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ws := New(WithServeFolder(t.TempDir()), WithAppPort("127.0.0.1:0"))
+			ws.accessLogOut = io.Discard
+			ws.build()
+			servers, err := ws.listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ws.servers = servers
+			go func() { _ = ws.server.Serve(servers[0].listener) }()
+			defer ws.server.Close()
+
+			ws.upgradeArgs = []string{"sh", "-c", tt.script}
+			_, err = ws.Upgrade(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WebServer.Upgrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ws.upgraded.Load() == tt.wantErr {
+				t.Errorf("WebServer.Upgrade() upgraded = %v, want %v", ws.upgraded.Load(), !tt.wantErr)
+			}
+			// the listener is still served after upgrading, until shut down
+			resp, err := http.Get("http://" + servers[0].listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// upgradeSignals are the signals to upgrade to a new process on
+var upgradeSignals = []os.Signal{syscall.SIGUSR2}
+
+// setNonblock sets the listener back to non-blocking, as passing its file to another
+// process sets the shared file description to blocking, which blocks closing it
+func setNonblock(l net.Listener) error {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var nonblockErr error
+	if err := rc.Control(func(fd uintptr) {
+		nonblockErr = syscall.SetNonblock(int(fd), true)
+	}); err != nil {
+		return err
+	}
+	return nonblockErr
+}
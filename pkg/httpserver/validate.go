@@ -0,0 +1,161 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/common"
+)
+
+// FileError is an error found in a config or map file, at a line when it is known
+type FileError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%v:%v: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	yamlLineRegexp      = regexp.MustCompile(`line (\d+): (.*)`)
+	jsonUnknownRegexp   = regexp.MustCompile(`json: (unknown field "([^"]+)")`)
+	jsonFieldTypeRegexp = regexp.MustCompile(`json: cannot unmarshal (\S+) into Go struct field [A-Za-z]*\.(\S+) of type (\S+)`)
+	parseErrorPrefix    = regexp.MustCompile(`^error (converting YAML to JSON|unmarshaling JSON)(: while decoding JSON)?: `)
+)
+
+// newFileError finds the line of a YAML or JSON parse error in the file content
+func newFileError(filePath string, content []byte, err error) *FileError {
+	msg := err.Error()
+	if m := yamlLineRegexp.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &FileError{Path: filePath, Line: line, Err: errors.New(m[2])}
+	}
+	if m := jsonUnknownRegexp.FindStringSubmatch(msg); m != nil {
+		return &FileError{Path: filePath, Line: lineOfKey(content, m[2]), Err: errors.New(m[1])}
+	}
+	if m := jsonFieldTypeRegexp.FindStringSubmatch(msg); m != nil {
+		key, _, _ := strings.Cut(m[2], ".")
+		return &FileError{Path: filePath, Line: lineOfKey(content, key), Err: fmt.Errorf("field %q: cannot use %v as %v", m[2], m[1], m[3])}
+	}
+	return &FileError{Path: filePath, Err: errors.New(parseErrorPrefix.ReplaceAllString(msg, ""))}
+}
+
+// lineOfKey returns the first line which sets the key, or zero when not found
+func lineOfKey(content []byte, key string) int {
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "{,- ")
+		if strings.HasPrefix(line, key+":") || strings.HasPrefix(line, `"`+key+`"`) || strings.HasPrefix(line, `'`+key+`'`) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// validateFile strictly parses a YAML or JSON file into the value
+func validateFile(filePath string, v any) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return &FileError{Path: filePath, Err: err}
+	}
+	if err := yaml.UnmarshalStrict(content, v); err != nil {
+		return newFileError(filePath, content, err)
+	}
+	return nil
+}
+
+// configFile is a map file or dotfile, which is parsed into value
+type configFile struct {
+	path     string
+	optional bool
+	value    any
+}
+
+// configFiles returns the map files in use and the dotfile, before any are loaded into c.
+// Files which needn't exist are optional
+func (w *WebServer) configFiles(c fileConfig) []configFile {
+	candidates := []struct {
+		enabled bool
+		configFile
+	}{
+		{
+			enabled: c.headerMapEnabled && c.headerMap == nil,
+			configFile: configFile{
+				path:     w.HeaderMapPath,
+				optional: w.configSources["headerMapPath"] == SourceDefault,
+				value:    &map[string][]string{},
+			},
+		},
+		{
+			enabled: w.TemplateMapEnabled && c.templateMap == nil,
+			configFile: configFile{
+				path:     w.TemplateMapPath,
+				optional: w.configSources["templateMapPath"] == SourceDefault,
+				value:    &map[string]string{},
+			},
+		},
+		{
+			enabled: w.RedirectRoutesEnabled && c.redirectRoutes == nil,
+			configFile: configFile{
+				path:     w.RedirectRoutesPath,
+				optional: w.configSources["redirectRoutesPath"] == SourceDefault,
+				value:    &map[string]string{},
+			},
+		},
+		{
+			enabled: true,
+			configFile: configFile{
+				path:     path.Join(w.ServeFolder, common.AppServeFolderConfigName),
+				optional: true,
+				value:    &common.DotfileConfig{},
+			},
+		},
+	}
+	files := []configFile{}
+	for _, candidate := range candidates {
+		if candidate.enabled && candidate.path != "" {
+			files = append(files, candidate.configFile)
+		}
+	}
+	return files
+}
+
+// validateFiles strictly parses each config file, returning every error found.
+// Files which are optional, or set in allowMissing, are skipped when missing
+func (w *WebServer) validateFiles(c fileConfig, allowMissing map[string]bool) []error {
+	errs := []error{}
+	for _, f := range w.configFiles(c) {
+		if _, err := os.Stat(f.path); errors.Is(err, os.ErrNotExist) && (f.optional || allowMissing[f.path]) {
+			continue
+		}
+		if err := validateFile(f.path, f.value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Validate parses the config file and each map file in use, returning every error found.
+// Map files left at their default paths are only checked when they exist
+func (w *WebServer) Validate() []error {
+	errs := []error{}
+	if w.configErr != nil {
+		errs = append(errs, w.configErr)
+	}
+	return append(errs, w.validateFiles(w.snapshotFileConfig(), nil)...)
+}
@@ -0,0 +1,230 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/certs"
+)
+
+// access log formats
+const (
+	AccessFormatJSON     = "json"
+	AccessFormatCombined = "combined"
+	AccessFormatTemplate = "template"
+)
+
+// combinedTimeFormat is the time format of the Apache combined log format
+const combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// Entry ...
+// an access log entry for a request
+type Entry struct {
+	Time       time.Time
+	Status     int
+	Method     string
+	Path       string
+	Query      string
+	Protocol   string
+	ClientIP   string
+	Bytes      int64
+	Duration   time.Duration
+	UserAgent  string
+	Referer    string
+	TLSVersion string
+	// ClientCertSubject and ClientCertSANs identify the verified client certificate, when one was given
+	ClientCertSubject string
+	ClientCertSANs    []string
+	Headers           http.Header
+}
+
+// AccessLogger ...
+// writes an access log entry for each request, as JSON, Apache combined or a template.
+// Headers and query parameters are redacted by the Redactor before the entry is written
+type AccessLogger struct {
+	Redactor *Redactor
+
+	format   string
+	template *template.Template
+	out      io.Writer
+	json     *slog.Logger
+	clientIP func(*http.Request) string
+	mu       sync.Mutex
+}
+
+// NewAccessLogger ...
+// returns an access logger writing to out in the format. The template, as a Go text template
+// of an Entry, is only used for the template format. The client IP is found with clientIP
+func NewAccessLogger(out io.Writer, format string, tmpl string, clientIP func(*http.Request) string) (*AccessLogger, error) {
+	a := &AccessLogger{
+		Redactor: NewRedactor(nil, nil, nil),
+		format:   format,
+		out:      out,
+		clientIP: clientIP,
+	}
+	switch format {
+	case AccessFormatJSON, "":
+		a.format = AccessFormatJSON
+		a.json = slog.New(slog.NewJSONHandler(out, nil))
+	case AccessFormatCombined:
+	case AccessFormatTemplate:
+		if tmpl == "" {
+			return nil, errors.New("an access log template is required for the template access log format")
+		}
+		t, err := template.New("access-log").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access log template: %w", err)
+		}
+		a.template = t
+	default:
+		return nil, fmt.Errorf("invalid access log format '%v', expected one of %v, %v or %v", format, AccessFormatJSON, AccessFormatCombined, AccessFormatTemplate)
+	}
+	return a, nil
+}
+
+// Log ...
+// writes the entry
+func (a *AccessLogger) Log(e Entry) {
+	switch a.format {
+	case AccessFormatJSON:
+		a.json.LogAttrs(context.Background(), slog.LevelInfo, "request",
+			slog.Int("status", e.Status),
+			slog.String("method", e.Method),
+			slog.String("path", e.Path),
+			slog.String("query", e.Query),
+			slog.String("protocol", e.Protocol),
+			slog.String("client_ip", e.ClientIP),
+			slog.Int64("bytes", e.Bytes),
+			slog.Float64("duration_seconds", e.Duration.Seconds()),
+			slog.String("user_agent", e.UserAgent),
+			slog.String("referer", e.Referer),
+			slog.String("tls_version", e.TLSVersion),
+			slog.String("client_cert_subject", e.ClientCertSubject),
+			slog.Any("client_cert_sans", e.ClientCertSANs),
+			slog.Any("headers", e.Headers),
+		)
+	case AccessFormatCombined:
+		a.write(combined(e))
+	case AccessFormatTemplate:
+		var b strings.Builder
+		if err := a.template.Execute(&b, e); err != nil {
+			slog.Error("failed to execute access log template", "error", err)
+			return
+		}
+		a.write(b.String() + "\n")
+	}
+}
+
+func (a *AccessLogger) write(line string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, _ = io.WriteString(a.out, line)
+}
+
+// combined formats the entry in the Apache combined log format
+func combined(e Entry) string {
+	uri := e.Path
+	if e.Query != "" {
+		uri += "?" + e.Query
+	}
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = fmt.Sprint(e.Bytes)
+	}
+	return fmt.Sprintf("%v - - [%v] \"%v %v %v\" %v %v %q %q\n",
+		e.ClientIP, e.Time.Format(combinedTimeFormat), e.Method, uri, e.Protocol, e.Status, bytes, orDash(e.Referer), orDash(e.UserAgent))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Middleware ...
+// logs each request once it is served
+func (a *AccessLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		e := Entry{
+			Time:      start,
+			Status:    recorder.status,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Query:     a.Redactor.Query(r.URL.RawQuery),
+			Protocol:  r.Proto,
+			ClientIP:  r.RemoteAddr,
+			Bytes:     recorder.bytes,
+			Duration:  time.Since(start),
+			UserAgent: r.UserAgent(),
+			Referer:   r.Referer(),
+			Headers:   a.Redactor.Header(r.Header),
+		}
+		if a.clientIP != nil {
+			e.ClientIP = a.clientIP(r)
+		}
+		if r.TLS != nil {
+			e.TLSVersion = tls.VersionName(r.TLS.Version)
+			e.ClientCertSubject, e.ClientCertSANs, _ = certs.ClientIdentity(r.TLS)
+		}
+		a.Log(e)
+	})
+}
+
+// responseRecorder records the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming responses
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports upgraded connections
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap supports http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLogger_Middleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		template  string
+		tls       bool
+		wantErr   bool
+		wantMatch string
+	}{
+		{
+			name:      "combined",
+			format:    AccessFormatCombined,
+			wantMatch: `^203\.0\.113\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /a\?b=c HTTP/1\.1" 201 5 "https://example\.com/" "test-agent"\n$`,
+		},
+		{
+			name:      "template",
+			format:    AccessFormatTemplate,
+			template:  `{{.Status}} {{.Method}} {{.Path}} {{.Query}} {{.Bytes}} {{.TLSVersion}}`,
+			tls:       true,
+			wantMatch: `^201 GET /a b=c 5 TLS 1\.3\n$`,
+		},
+		{
+			name:    "template without a template",
+			format:  AccessFormatTemplate,
+			wantErr: true,
+		},
+		{
+			name:     "invalid template",
+			format:   AccessFormatTemplate,
+			template: `{{.Status`,
+			wantErr:  true,
+		},
+		{
+			name:    "invalid format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out := &bytes.Buffer{}
+			a, err := NewAccessLogger(out, tt.format, tt.template, func(r *http.Request) string { return "203.0.113.1" })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAccessLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			serveExample(a, tt.tls)
+			if !regexp.MustCompile(tt.wantMatch).MatchString(out.String()) {
+				t.Errorf("AccessLogger.Middleware() = %q, want to match %v", out.String(), tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestAccessLogger_Middleware_json(t *testing.T) {
+	out := &bytes.Buffer{}
+	a, err := NewAccessLogger(out, AccessFormatJSON, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveExample(a, true)
+	entry := map[string]any{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("AccessLogger.Middleware() = %v, not JSON: %v", out.String(), err)
+	}
+	want := map[string]any{
+		"msg":                 "request",
+		"status":              201.0,
+		"method":              "GET",
+		"path":                "/a",
+		"query":               "b=c",
+		"protocol":            "HTTP/1.1",
+		"client_ip":           "192.0.2.1:1234",
+		"bytes":               5.0,
+		"user_agent":          "test-agent",
+		"referer":             "https://example.com/",
+		"tls_version":         "TLS 1.3",
+		"client_cert_subject": "CN=client",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("AccessLogger.Middleware() %v = %v, want %v", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["duration_seconds"]; !ok {
+		t.Errorf("AccessLogger.Middleware() duration_seconds missing")
+	}
+	if got := fmt.Sprint(entry["client_cert_sans"]); got != "[client.example.com]" {
+		t.Errorf("AccessLogger.Middleware() client_cert_sans = %v, want [client.example.com]", got)
+	}
+	headers, _ := entry["headers"].(map[string]any)
+	if got := fmt.Sprint(headers["Authorization"]); got != "["+Redacted+"]" {
+		t.Errorf("AccessLogger.Middleware() headers Authorization = %v, want redacted", got)
+	}
+}
+
+func serveExample(a *AccessLogger, withTLS bool) {
+	req := httptest.NewRequest(http.MethodGet, "/a?b=c", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("Authorization", "Bearer abc")
+	if withTLS {
+		req.TLS = &tls.ConnectionState{
+			Version:        tls.VersionTLS13,
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "client"}, DNSNames: []string{"client.example.com"}}}},
+		}
+	}
+	a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
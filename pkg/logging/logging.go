@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel ...
+// parses a level of debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("invalid log level '%v', expected one of debug, info, warn or error", level)
+	}
+	return l, nil
+}
+
+// NewLogger ...
+// returns a leveled logger writing text or JSON to out
+func NewLogger(out io.Writer, format string, level string) (*slog.Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		return slog.New(slog.NewTextHandler(out, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(out, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format '%v', expected one of %v or %v", format, FormatText, FormatJSON)
+}
+
+// SetDefault ...
+// sets the default logger, which the log package also writes through
+func SetDefault(out io.Writer, format string, level string) error {
+	logger, err := NewLogger(out, format, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
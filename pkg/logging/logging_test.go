@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		level        string
+		wantErr      bool
+		wantContains string
+		wantDebug    bool
+	}{
+		{
+			name:         "text",
+			format:       FormatText,
+			level:        "info",
+			wantContains: `level=INFO msg=hello`,
+		},
+		{
+			name:         "json",
+			format:       FormatJSON,
+			level:        "info",
+			wantContains: `"level":"INFO","msg":"hello"`,
+		},
+		{
+			name:         "debug",
+			format:       FormatText,
+			level:        "debug",
+			wantContains: `level=INFO msg=hello`,
+			wantDebug:    true,
+		},
+		{
+			name:    "invalid level",
+			format:  FormatText,
+			level:   "loud",
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			format:  "xml",
+			level:   "info",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out := &bytes.Buffer{}
+			logger, err := NewLogger(out, tt.format, tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLogger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			logger.Info("hello")
+			logger.Debug("debugging")
+			if !strings.Contains(out.String(), tt.wantContains) {
+				t.Errorf("NewLogger() output = %v, want to contain %v", out.String(), tt.wantContains)
+			}
+			if got := strings.Contains(out.String(), "debugging"); got != tt.wantDebug {
+				t.Errorf("NewLogger() debug logged = %v, want %v", got, tt.wantDebug)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if got, err := ParseLevel("WARN"); err != nil || got != slog.LevelWarn {
+		t.Errorf("ParseLevel() = %v, %v, want %v", got, err, slog.LevelWarn)
+	}
+}
@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted is logged in place of a redacted value
+const Redacted = "REDACTED"
+
+// DefaultRedactHeaders are the headers which are always redacted
+var DefaultRedactHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Amz-Security-Token",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Csrf-Token",
+	"X-Xsrf-Token",
+}
+
+// Redactor ...
+// redacts sensitive headers and query parameters, before they are logged
+type Redactor struct {
+	redactHeaders map[string]bool
+	allowHeaders  map[string]bool
+	maskQuery     map[string]bool
+	maskAllQuery  bool
+}
+
+// NewRedactor ...
+// returns a redactor of the headers in redactHeaders, along with the DefaultRedactHeaders.
+// When allowHeaders is set, only those headers are kept. Values of the query parameters in
+// maskQueryParams are masked, with * masking every parameter
+func NewRedactor(redactHeaders []string, allowHeaders []string, maskQueryParams []string) *Redactor {
+	r := &Redactor{
+		redactHeaders: map[string]bool{},
+		maskQuery:     map[string]bool{},
+	}
+	for _, h := range append(append([]string{}, DefaultRedactHeaders...), redactHeaders...) {
+		r.redactHeaders[http.CanonicalHeaderKey(strings.TrimSpace(h))] = true
+	}
+	for _, h := range allowHeaders {
+		if r.allowHeaders == nil {
+			r.allowHeaders = map[string]bool{}
+		}
+		r.allowHeaders[http.CanonicalHeaderKey(strings.TrimSpace(h))] = true
+	}
+	for _, p := range maskQueryParams {
+		if p == "*" {
+			r.maskAllQuery = true
+		}
+		r.maskQuery[p] = true
+	}
+	return r
+}
+
+// Header ...
+// returns a copy of the headers, without those not allowed and with redacted values
+func (r *Redactor) Header(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		k = http.CanonicalHeaderKey(k)
+		if r.allowHeaders != nil && !r.allowHeaders[k] {
+			continue
+		}
+		if r.redactHeaders[k] {
+			out[k] = []string{Redacted}
+			continue
+		}
+		out[k] = append([]string{}, v...)
+	}
+	return out
+}
+
+// Query ...
+// returns the raw query with the values of masked parameters redacted
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" || (!r.maskAllQuery && len(r.maskQuery) == 0) {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// an unparsable query may hold anything, so mask all of it
+		return Redacted
+	}
+	for k, v := range values {
+		if !r.maskAllQuery && !r.maskQuery[k] {
+			continue
+		}
+		for i := range v {
+			v[i] = Redacted
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRedactor_Header(t *testing.T) {
+	header := http.Header{
+		"Authorization": {"Bearer abc"},
+		"Cookie":        {"session=abc"},
+		"User-Agent":    {"test-agent"},
+		"X-Session":     {"abc"},
+	}
+	tests := []struct {
+		name          string
+		redactHeaders []string
+		allowHeaders  []string
+		want          http.Header
+	}{
+		{
+			name: "default headers",
+			want: http.Header{
+				"Authorization": {Redacted},
+				"Cookie":        {Redacted},
+				"User-Agent":    {"test-agent"},
+				"X-Session":     {"abc"},
+			},
+		},
+		{
+			name:          "additional headers",
+			redactHeaders: []string{"x-session"},
+			want: http.Header{
+				"Authorization": {Redacted},
+				"Cookie":        {Redacted},
+				"User-Agent":    {"test-agent"},
+				"X-Session":     {Redacted},
+			},
+		},
+		{
+			name:         "allowed headers",
+			allowHeaders: []string{"user-agent", "Authorization"},
+			want: http.Header{
+				"Authorization": {Redacted},
+				"User-Agent":    {"test-agent"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := NewRedactor(tt.redactHeaders, tt.allowHeaders, nil).Header(header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redactor.Header() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("Redactor.Header() modified the request headers")
+	}
+}
+
+func TestRedactor_Query(t *testing.T) {
+	tests := []struct {
+		name            string
+		maskQueryParams []string
+		query           string
+		want            string
+	}{
+		{
+			name:  "no masked params",
+			query: "token=abc&page=2",
+			want:  "token=abc&page=2",
+		},
+		{
+			name:            "masked param",
+			maskQueryParams: []string{"token"},
+			query:           "token=abc&page=2",
+			want:            "page=2&token=" + Redacted,
+		},
+		{
+			name:            "all params",
+			maskQueryParams: []string{"*"},
+			query:           "token=abc&page=2",
+			want:            "page=" + Redacted + "&token=" + Redacted,
+		},
+		{
+			name:            "invalid query",
+			maskQueryParams: []string{"token"},
+			query:           "token=%zz",
+			want:            Redacted,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := NewRedactor(nil, nil, tt.maskQueryParams).Query(tt.query); got != tt.want {
+				t.Errorf("Redactor.Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
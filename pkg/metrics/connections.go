@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// reasons for rejecting connections
+const (
+	RejectReasonMaxConnections      = "max_connections"
+	RejectReasonMaxConnectionsPerIP = "max_connections_per_ip"
+)
+
+var (
+	connectionsActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Name:      "connections_active",
+		Help:      "The number of open connections, by listener",
+	}, []string{"listener"})
+	connectionsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ghs",
+		Name:      "connections_rejected_total",
+		Help:      "The number of connections rejected for being over a limit, by listener and reason",
+	}, []string{"listener", "reason"})
+)
+
+// AddActiveConnections ...
+// adds to the number of open connections on a listener, with a negative delta when closed
+func AddActiveConnections(listener string, delta float64) {
+	connectionsActive.WithLabelValues(listener).Add(delta)
+}
+
+// RecordConnectionRejected ...
+// records a connection rejected for being over a limit
+func RecordConnectionRejected(listener string, reason string) {
+	connectionsRejected.WithLabelValues(listener, reason).Inc()
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordConnectionRejected(t *testing.T) {
+	for _, reason := range []string{RejectReasonMaxConnections, RejectReasonMaxConnectionsPerIP} {
+		before := testutil.ToFloat64(connectionsRejected.WithLabelValues("test", reason))
+		RecordConnectionRejected("test", reason)
+		if got := testutil.ToFloat64(connectionsRejected.WithLabelValues("test", reason)); got != before+1 {
+			t.Errorf("connections rejected %v = %v, want %v", reason, got, before+1)
+		}
+	}
+}
+
+func TestAddActiveConnections(t *testing.T) {
+	AddActiveConnections("test", 2)
+	AddActiveConnections("test", -1)
+	if got := testutil.ToFloat64(connectionsActive.WithLabelValues("test")); got != 1 {
+		t.Errorf("connections active = %v, want 1", got)
+	}
+}
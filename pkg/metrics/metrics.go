@@ -2,69 +2,107 @@ package metrics
 
 import (
 	"context"
-	"log"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gitlab.com/BobyMCbobs/go-http-server/pkg/adminserver"
+)
+
+// shutdown phases
+const (
+	ShutdownPhaseDraining = "draining"
+	ShutdownPhaseShutdown = "shutdown"
+	ShutdownPhaseStopped  = "stopped"
+)
+
+var (
+	shutdownPhases = []string{ShutdownPhaseDraining, ShutdownPhaseShutdown, ShutdownPhaseStopped}
+
+	shutdownPhase = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Name:      "shutdown_phase",
+		Help:      "The current phase of shutting down, set to 1 for the active phase",
+	}, []string{"phase"})
+	shutdownPhaseStartedTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Name:      "shutdown_phase_started_timestamp_seconds",
+		Help:      "The unix time which each phase of shutting down started at",
+	}, []string{"phase"})
 )
 
 // Metrics configures the metrics handler
 type Metrics struct {
-	Enabled bool
-	Port    string
+	Enabled         bool
+	Port            string
+	ShutdownTimeout time.Duration
+	// ConfigureServer sets the timeouts and limits of the server, in place of the defaults
+	ConfigureServer func(*http.Server)
+
+	server adminserver.Server
 }
 
-// Handle ...
-// HTTP handler for metrics
-func (m *Metrics) Handle(ch ...<-chan bool) {
-	if !m.Enabled {
-		return
+// SetShutdownPhase ...
+// records the active phase of shutting down
+func SetShutdownPhase(phase string) {
+	for _, p := range shutdownPhases {
+		shutdownPhase.WithLabelValues(p).Set(0)
 	}
+	shutdownPhase.WithLabelValues(phase).Set(1)
+	shutdownPhaseStartedTime.WithLabelValues(phase).SetToCurrentTime()
+}
 
+// Handler ...
+// returns the router for the metrics endpoint
+func (m *Metrics) Handler() http.Handler {
 	router := mux.NewRouter().StrictSlash(true)
-	r := router.Handle("/metrics", promhttp.Handler())
-	server := &http.Server{
-		Handler:           r.GetHandler(),
-		Addr:              m.Port,
-		WriteTimeout:      15 * time.Second,
-		ReadTimeout:       15 * time.Second,
-		ReadHeaderTimeout: 10 * time.Second,
+	router.Handle("/metrics", promhttp.Handler())
+	return router
+}
+
+// adminConfig returns the config of the server of the metrics endpoint
+func (m *Metrics) adminConfig() adminserver.Config {
+	return adminserver.Config{
+		Name:            "metrics",
+		Port:            m.Port,
+		ShutdownTimeout: m.ShutdownTimeout,
+		ConfigureServer: m.ConfigureServer,
+		Handler:         m.Handler(),
 	}
-	log.Printf("Metrics listening on %v\n", server.Addr)
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// Serve ...
+// serves metrics on the listener until shut down
+func (m *Metrics) Serve(l net.Listener) error {
+	return m.server.Serve(m.adminConfig(), l)
+}
 
-	go func() {
-		if len(ch) == 0 {
-			return
-		}
-		for {
-			c, ok := <-ch[0]
-			log.Println("<- receieved event:", c, ok)
-			if !ok {
-				break
-			}
-			if c {
-				done <- os.Interrupt
-			}
-		}
-	}()
+// Shutdown ...
+// gracefully stops serving metrics
+func (m *Metrics) Shutdown(ctx context.Context) error {
+	return m.server.Shutdown(ctx)
+}
 
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal(err)
-		}
-	}()
+// Start ...
+// serves metrics on the port until the context is cancelled
+func (m *Metrics) Start(ctx context.Context) error {
+	if !m.Enabled {
+		return nil
+	}
+	return m.server.Start(ctx, m.adminConfig())
+}
 
-	<-done
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server didn't exit gracefully %v", err)
+// Handle ...
+// serves metrics on the port until interrupted, or until true is received on the channel
+//
+// Deprecated: use Start, which returns errors and leaves handling signals to the caller
+func (m *Metrics) Handle(ch ...<-chan bool) {
+	if !m.Enabled {
+		return
 	}
+	m.server.Handle(m.adminConfig(), ch...)
 }
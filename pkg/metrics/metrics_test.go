@@ -1,21 +1,26 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"net"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
-func TestMetrics_Handle(t *testing.T) {
+func TestMetrics_Start(t *testing.T) {
 	type fields struct {
 		Enabled bool
 		Port    string
 	}
 	tests := []struct {
-		name          string
-		noQuitChannel bool
-		fields        fields
+		name      string
+		fields    fields
+		portInUse bool
+		wantErr   bool
 	}{
 		{
 			name: "basic",
@@ -31,25 +36,73 @@ func TestMetrics_Handle(t *testing.T) {
 				Port:    fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
 			},
 		},
+		{
+			name: "port in use",
+			fields: fields{
+				Enabled: true,
+				Port:    fmt.Sprintf(":%v", rand.Intn(65000-50000)+50000),
+			},
+			portInUse: true,
+			wantErr:   true,
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
+			if tt.portInUse {
+				l, err := net.Listen("tcp", tt.fields.Port)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer l.Close()
+			}
 			m := &Metrics{
 				Enabled: tt.fields.Enabled,
 				Port:    tt.fields.Port,
 			}
-			quitChan := make(chan bool, 1)
-			if tt.noQuitChannel {
-				quitChan = nil
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+			if err := m.Start(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Metrics.Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetShutdownPhase(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase string
+	}{
+		{
+			name:  "draining",
+			phase: ShutdownPhaseDraining,
+		},
+		{
+			name:  "shutdown",
+			phase: ShutdownPhaseShutdown,
+		},
+		{
+			name:  "stopped",
+			phase: ShutdownPhaseStopped,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			SetShutdownPhase(tt.phase)
+			for _, p := range shutdownPhases {
+				want := 0.0
+				if p == tt.phase {
+					want = 1
+				}
+				if got := testutil.ToFloat64(shutdownPhase.WithLabelValues(p)); got != want {
+					t.Errorf("shutdown phase %v = %v, want %v", p, got, want)
+				}
 			}
-			go m.Handle(quitChan)
-			time.Sleep(1 * time.Second)
-			if !tt.noQuitChannel {
-				defer func() {
-					quitChan <- true
-				}()
+			if got := testutil.ToFloat64(shutdownPhaseStartedTime.WithLabelValues(tt.phase)); got == 0 {
+				t.Errorf("shutdown phase %v started time not set", tt.phase)
 			}
 		})
 	}
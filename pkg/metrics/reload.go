@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// reload results
+const (
+	ReloadResultSuccess = "success"
+	ReloadResultFailure = "failure"
+)
+
+var (
+	configReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ghs",
+		Name:      "config_reloads_total",
+		Help:      "The number of config reloads, by result",
+	}, []string{"result"})
+	configLastReloadSuccessful = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last config reload succeeded, set to 1 on success and 0 on failure",
+	})
+	configLastReloadSuccessTime = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "The unix time of the last successful config reload",
+	})
+)
+
+// RecordConfigReload ...
+// records the result of a config reload, failing when err is set
+func RecordConfigReload(err error) {
+	if err != nil {
+		configReloads.WithLabelValues(ReloadResultFailure).Inc()
+		configLastReloadSuccessful.Set(0)
+		return
+	}
+	configReloads.WithLabelValues(ReloadResultSuccess).Inc()
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTime.SetToCurrentTime()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordConfigReload(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantResult     string
+		wantSuccessful float64
+	}{
+		{
+			name:           "success",
+			wantResult:     ReloadResultSuccess,
+			wantSuccessful: 1,
+		},
+		{
+			name:           "failure",
+			err:            fmt.Errorf("invalid config"),
+			wantResult:     ReloadResultFailure,
+			wantSuccessful: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(configReloads.WithLabelValues(tt.wantResult))
+			RecordConfigReload(tt.err)
+			if got := testutil.ToFloat64(configReloads.WithLabelValues(tt.wantResult)); got != before+1 {
+				t.Errorf("config reloads %v = %v, want %v", tt.wantResult, got, before+1)
+			}
+			if got := testutil.ToFloat64(configLastReloadSuccessful); got != tt.wantSuccessful {
+				t.Errorf("config last reload successful = %v, want %v", got, tt.wantSuccessful)
+			}
+		})
+	}
+}
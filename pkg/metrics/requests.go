@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// serving modes, for labelling how a request was served
+const (
+	ServingModeStatic       = "static"
+	ServingModeTemplate     = "template"
+	ServingModeRedirect     = "redirect"
+	ServingMode404          = "404"
+	ServingModeExtraHandler = "extra"
+	ServingModeUnknown      = "unknown"
+)
+
+// listeners, for labelling which server a request was received on
+const (
+	ListenerHTTP  = "http"
+	ListenerHTTPS = "https"
+	ListenerHTTP3 = "http3"
+)
+
+var (
+	requestLabels = []string{"code", "method", "mode", "listener", "protocol"}
+
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ghs",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "The number of HTTP requests served",
+	}, requestLabels)
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ghs",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "The time taken to serve HTTP requests",
+		Buckets:   prometheus.DefBuckets,
+	}, requestLabels)
+	responseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "ghs",
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "The size of HTTP response bodies",
+		Buckets:   prometheus.ExponentialBuckets(100, 10, 7),
+	}, requestLabels)
+	requestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "The number of HTTP requests currently being served",
+	}, []string{"listener"})
+
+	// knownMethods bounds the method label, all others are recorded as OTHER
+	knownMethods = map[string]bool{
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodPost:    true,
+		http.MethodPut:     true,
+		http.MethodPatch:   true,
+		http.MethodDelete:  true,
+		http.MethodConnect: true,
+		http.MethodOptions: true,
+		http.MethodTrace:   true,
+	}
+
+	// knownProtocols bounds the protocol label, all others are recorded as OTHER
+	knownProtocols = map[string]bool{
+		"HTTP/1.0": true,
+		"HTTP/1.1": true,
+		"HTTP/2.0": true,
+		"HTTP/3.0": true,
+	}
+)
+
+type servingModeKey struct{}
+
+// SetServingMode ...
+// records how a request is being served, for labelling request metrics
+func SetServingMode(r *http.Request, mode string) {
+	if m, ok := r.Context().Value(servingModeKey{}).(*string); ok {
+		*m = mode
+	}
+}
+
+// ServingModeHandler ...
+// wraps a handler to record the mode it serves requests with
+func ServingModeHandler(mode string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetServingMode(r, mode)
+		next.ServeHTTP(w, r)
+	})
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	written     int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.written += n
+	return n, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware ...
+// records request counts, latency, response sizes and in-flight requests
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listener := listenerLabel(r)
+		inFlight := requestsInFlight.WithLabelValues(listener)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		mode := ServingModeUnknown
+		r = r.WithContext(context.WithValue(r.Context(), servingModeKey{}, &mode))
+		recorder := &responseRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		labels := prometheus.Labels{
+			"code":     statusClass(recorder.status),
+			"method":   methodLabel(r.Method),
+			"mode":     mode,
+			"listener": listener,
+			"protocol": protocolLabel(r.Proto),
+		}
+		requestsTotal.With(labels).Inc()
+		requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		responseSize.With(labels).Observe(float64(recorder.written))
+	})
+}
+
+// listenerLabel returns which listener the request was received on
+func listenerLabel(r *http.Request) string {
+	if r.ProtoMajor == 3 {
+		return ListenerHTTP3
+	}
+	if r.TLS != nil {
+		return ListenerHTTPS
+	}
+	return ListenerHTTP
+}
+
+// statusClass returns the class of a status code, such as 2xx
+func statusClass(status int) string {
+	switch {
+	case status >= 100 && status < 200:
+		return "1xx"
+	case status >= 200 && status < 300:
+		return "2xx"
+	case status >= 300 && status < 400:
+		return "3xx"
+	case status >= 400 && status < 500:
+		return "4xx"
+	case status >= 500 && status < 600:
+		return "5xx"
+	}
+	return "unknown"
+}
+
+// methodLabel returns the method, or OTHER for non-standard methods
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
+
+// protocolLabel returns the protocol, or OTHER for unknown protocols
+func protocolLabel(proto string) string {
+	if knownProtocols[proto] {
+		return proto
+	}
+	return "OTHER"
+}
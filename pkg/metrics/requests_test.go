@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		tls        bool
+		proto      int
+		handler    http.Handler
+		wantLabels prometheus.Labels
+		wantSize   int
+	}{
+		{
+			name:   "basic",
+			method: http.MethodGet,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetServingMode(r, ServingModeStatic)
+				_, _ = w.Write([]byte("hello"))
+			}),
+			wantLabels: prometheus.Labels{"code": "2xx", "method": "GET", "mode": "static", "listener": "http", "protocol": "HTTP/1.1"},
+			wantSize:   5,
+		},
+		{
+			name:   "404 over tls",
+			method: http.MethodGet,
+			tls:    true,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetServingMode(r, ServingMode404)
+				w.WriteHeader(http.StatusNotFound)
+			}),
+			wantLabels: prometheus.Labels{"code": "4xx", "method": "GET", "mode": "404", "listener": "https", "protocol": "HTTP/1.1"},
+		},
+		{
+			name:       "extra handler",
+			method:     http.MethodPost,
+			handler:    ServingModeHandler(ServingModeExtraHandler, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+			wantLabels: prometheus.Labels{"code": "2xx", "method": "POST", "mode": "extra", "listener": "http", "protocol": "HTTP/1.1"},
+		},
+		{
+			name:   "unknown mode and non-standard method",
+			method: "PURGE",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}),
+			wantLabels: prometheus.Labels{"code": "5xx", "method": "OTHER", "mode": "unknown", "listener": "http", "protocol": "HTTP/1.1"},
+		},
+		{
+			name:   "HTTP/2",
+			method: http.MethodGet,
+			tls:    true,
+			proto:  2,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetServingMode(r, ServingModeStatic)
+			}),
+			wantLabels: prometheus.Labels{"code": "2xx", "method": "GET", "mode": "static", "listener": "https", "protocol": "HTTP/2.0"},
+		},
+		{
+			name:   "HTTP/3",
+			method: http.MethodGet,
+			tls:    true,
+			proto:  3,
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetServingMode(r, ServingModeStatic)
+			}),
+			wantLabels: prometheus.Labels{"code": "2xx", "method": "GET", "mode": "static", "listener": "http3", "protocol": "HTTP/3.0"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(requestsTotal.With(tt.wantLabels))
+			req := httptest.NewRequest(tt.method, "/some/path?a=b", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != 0 {
+				req.ProtoMajor, req.ProtoMinor = tt.proto, 0
+				req.Proto = fmt.Sprintf("HTTP/%v.0", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			Middleware(tt.handler).ServeHTTP(rec, req)
+			if got := testutil.ToFloat64(requestsTotal.With(tt.wantLabels)); got != before+1 {
+				t.Errorf("requests total %v = %v, want %v", tt.wantLabels, got, before+1)
+			}
+			if got := rec.Body.Len(); got != tt.wantSize {
+				t.Errorf("response size = %v, want %v", got, tt.wantSize)
+			}
+			if got := testutil.ToFloat64(requestsInFlight.WithLabelValues(tt.wantLabels["listener"])); got != 0 {
+				t.Errorf("requests in flight = %v, want %v", got, 0)
+			}
+		})
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusContinue, want: "1xx"},
+		{status: http.StatusOK, want: "2xx"},
+		{status: http.StatusTemporaryRedirect, want: "3xx"},
+		{status: http.StatusNotFound, want: "4xx"},
+		{status: http.StatusBadGateway, want: "5xx"},
+		{status: 999, want: "unknown"},
+	}
+	for _, tt := range tests {
+		if got := statusClass(tt.status); got != tt.want {
+			t.Errorf("statusClass(%v) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
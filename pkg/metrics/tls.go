@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	certificateExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "ghs",
+		Name:      "tls_certificate_expiry_timestamp_seconds",
+		Help:      "The unix time the served TLS certificate expires, by certificate path",
+	}, []string{"path"})
+	certificateReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ghs",
+		Name:      "tls_certificate_reloads_total",
+		Help:      "The number of TLS certificate loads, by result",
+	}, []string{"result"})
+	tlsHandshakes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ghs",
+		Name:      "tls_handshakes_total",
+		Help:      "The number of TLS handshakes, by negotiated version and cipher suite",
+	}, []string{"version", "cipher"})
+)
+
+// SetCertificateExpiry ...
+// sets the time the certificate served from the path expires
+func SetCertificateExpiry(path string, notAfter time.Time) {
+	certificateExpiry.WithLabelValues(path).Set(float64(notAfter.Unix()))
+}
+
+// DeleteCertificateExpiry ...
+// removes the expiry of the certificate from the path, once it's no longer served
+func DeleteCertificateExpiry(path string) {
+	certificateExpiry.DeleteLabelValues(path)
+}
+
+// RecordCertificateReload ...
+// records the result of loading a TLS certificate, failing when err is set
+func RecordCertificateReload(err error) {
+	if err != nil {
+		certificateReloads.WithLabelValues(ReloadResultFailure).Inc()
+		return
+	}
+	certificateReloads.WithLabelValues(ReloadResultSuccess).Inc()
+}
+
+// RecordTLSHandshake ...
+// records a TLS handshake with the negotiated version and cipher suite
+func RecordTLSHandshake(version uint16, cipherSuite uint16) {
+	tlsHandshakes.WithLabelValues(tls.VersionName(version), tls.CipherSuiteName(cipherSuite)).Inc()
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetCertificateExpiry(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	SetCertificateExpiry("/tls/tls.crt", notAfter)
+	if got := testutil.ToFloat64(certificateExpiry.WithLabelValues("/tls/tls.crt")); got != float64(notAfter.Unix()) {
+		t.Errorf("certificate expiry = %v, want %v", got, notAfter.Unix())
+	}
+}
+
+func TestDeleteCertificateExpiry(t *testing.T) {
+	SetCertificateExpiry("/tls/removed.crt", time.Now())
+	before := testutil.CollectAndCount(certificateExpiry)
+	DeleteCertificateExpiry("/tls/removed.crt")
+	if got := testutil.CollectAndCount(certificateExpiry); got != before-1 {
+		t.Errorf("certificate expiry series = %v, want %v", got, before-1)
+	}
+}
+
+func TestRecordCertificateReload(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantResult string
+	}{
+		{
+			name:       "success",
+			wantResult: ReloadResultSuccess,
+		},
+		{
+			name:       "failure",
+			err:        fmt.Errorf("invalid certificate"),
+			wantResult: ReloadResultFailure,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(certificateReloads.WithLabelValues(tt.wantResult))
+			RecordCertificateReload(tt.err)
+			if got := testutil.ToFloat64(certificateReloads.WithLabelValues(tt.wantResult)); got != before+1 {
+				t.Errorf("certificate reloads %v = %v, want %v", tt.wantResult, got, before+1)
+			}
+		})
+	}
+}
+
+func TestRecordTLSHandshake(t *testing.T) {
+	counter := tlsHandshakes.WithLabelValues("TLS 1.3", "TLS_AES_128_GCM_SHA256")
+	before := testutil.ToFloat64(counter)
+	RecordTLSHandshake(tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256)
+	if got := testutil.ToFloat64(counter); got != before+1 {
+		t.Errorf("TLS handshakes = %v, want %v", got, before+1)
+	}
+}
@@ -0,0 +1,216 @@
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// headers which the client IP is found in by default, from trusted proxies
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+)
+
+type contextKey struct{}
+
+// Resolver ...
+// resolves the client IP of a request, from headers set by trusted proxies
+type Resolver struct {
+	// Header is the header to find the client IP in. When empty, the Forwarded header
+	// is used, and then the X-Forwarded-For header
+	Header string
+	// TrustedProxies are the networks of the proxies which are trusted to set the header.
+	// When empty, the header is ignored from every peer
+	TrustedProxies []netip.Prefix
+}
+
+// NewResolver ...
+// returns a resolver of the client IP, from the header set by the trusted proxies,
+// given as CIDRs or IPs
+func NewResolver(header string, trustedProxies []string) (*Resolver, error) {
+	prefixes, err := ParseTrustedProxies(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{Header: header, TrustedProxies: prefixes}, nil
+}
+
+// ParseTrustedProxies ...
+// parses CIDRs or IPs of trusted proxies
+func ParseTrustedProxies(trustedProxies []string) (prefixes []netip.Prefix, err error) {
+	for _, p := range trustedProxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%v': %w", p, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%v': %w", p, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// trusted returns if the address is of a trusted proxy
+func (r *Resolver) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range r.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP ...
+// returns the client IP of the request. The chain of addresses in the header is walked from
+// right to left, from the peer, skipping trusted proxies, returning the first untrusted address.
+// Headers from an untrusted peer, or from every peer without trusted proxies, are ignored
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote, ok := parseAddr(req.RemoteAddr)
+	if !ok {
+		return req.RemoteAddr
+	}
+	if !r.trusted(remote) {
+		return remote.String()
+	}
+	client := remote
+	hops := r.hops(req.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			// an obfuscated or invalid hop can't be trusted or walked past
+			break
+		}
+		client = addr
+		if !r.trusted(addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// hops returns the addresses in the header, from the client to the nearest proxy
+func (r *Resolver) hops(header http.Header) []string {
+	switch http.CanonicalHeaderKey(r.Header) {
+	case HeaderForwarded:
+		return ParseForwarded(header.Values(HeaderForwarded))
+	case "":
+		if values := header.Values(HeaderForwarded); len(values) > 0 {
+			return ParseForwarded(values)
+		}
+		return splitList(header.Values(HeaderXForwardedFor))
+	}
+	return splitList(header.Values(r.Header))
+}
+
+// splitList returns the comma separated values of each header line
+func splitList(values []string) (list []string) {
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list
+}
+
+// ParseForwarded ...
+// returns the for parameter of each element of RFC 7239 Forwarded headers, in order.
+// Elements without a for parameter are returned as empty
+func ParseForwarded(values []string) (list []string) {
+	for _, v := range values {
+		for _, element := range splitQuoted(v, ',') {
+			forValue := ""
+			for _, pair := range splitQuoted(element, ';') {
+				k, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(k, "for") {
+					continue
+				}
+				forValue = strings.Trim(strings.TrimSpace(value), `"`)
+			}
+			list = append(list, forValue)
+		}
+	}
+	return list
+}
+
+// splitQuoted splits s by sep, outside of quoted strings
+func splitQuoted(s string, sep byte) (parts []string) {
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseAddr parses an IP, with an optional port and IPv6 brackets
+func parseAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// Middleware ...
+// resolves the client IP of each request, storing it in the request context
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), r.ClientIP(req))))
+	})
+}
+
+// NewContext ...
+// returns a context with the client IP
+func NewContext(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, contextKey{}, clientIP)
+}
+
+// FromContext ...
+// returns the client IP stored in the context
+func FromContext(ctx context.Context) (clientIP string, ok bool) {
+	clientIP, ok = ctx.Value(contextKey{}).(string)
+	return clientIP, ok
+}
+
+// FromRequest ...
+// returns the client IP stored in the request context, otherwise the remote address
+func FromRequest(req *http.Request) string {
+	if clientIP, ok := FromContext(req.Context()); ok {
+		return clientIP
+	}
+	if addr, ok := parseAddr(req.RemoteAddr); ok {
+		return addr.String()
+	}
+	return req.RemoteAddr
+}
@@ -0,0 +1,167 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		trustedProxies []string
+		remoteAddr     string
+		headers        map[string][]string
+		want           string
+	}{
+		{
+			name:       "no header",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "header ignored without trusted proxies",
+			header:     "X-Real-Ip",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "forged x-forwarded-for without trusted proxies",
+			header:     "X-Forwarded-For",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"127.0.0.1, 10.0.0.1"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:           "untrusted peer",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "192.0.2.1:1234",
+			headers:        map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:           "192.0.2.1",
+		},
+		{
+			name:           "x-forwarded-for walked right to left",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{"X-Forwarded-For": {"203.0.113.9, 198.51.100.1", "10.0.0.2"}},
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "every hop trusted",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:           "10.0.0.3",
+		},
+		{
+			name:           "invalid hop",
+			trustedProxies: []string{"10.0.0.1"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{"X-Forwarded-For": {"198.51.100.1, nope"}},
+			want:           "10.0.0.1",
+		},
+		{
+			name:           "forwarded",
+			trustedProxies: []string{"10.0.0.0/8", "2001:db8::/32"},
+			remoteAddr:     "[2001:db8::1]:1234",
+			headers: map[string][]string{"Forwarded": {
+				`for=198.51.100.1;proto=https, for="[2001:db8:cafe::17]:4711"`,
+				`for=10.0.0.2;by=10.0.0.1`,
+			}},
+			want: "198.51.100.1",
+		},
+		{
+			name:           "forwarded with an obfuscated hop",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string][]string{"Forwarded": {`for=_hidden, for=10.0.0.2`}},
+			want:           "10.0.0.2",
+		},
+		{
+			name:           "forwarded preferred over x-forwarded-for",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=198.51.100.1"},
+				"X-Forwarded-For": {"203.0.113.9"},
+			},
+			want: "198.51.100.1",
+		},
+		{
+			name:           "configured header",
+			header:         "X-Forwarded-For",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=198.51.100.1"},
+				"X-Forwarded-For": {"203.0.113.9"},
+			},
+			want: "203.0.113.9",
+		},
+		{
+			name:           "ipv4 mapped ipv6 peer",
+			trustedProxies: []string{"10.0.0.1"},
+			remoteAddr:     "[::ffff:10.0.0.1]:1234",
+			headers:        map[string][]string{"X-Forwarded-For": {"198.51.100.1"}},
+			want:           "198.51.100.1",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r, err := NewResolver(tt.header, tt.trustedProxies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header = tt.headers
+			if got := r.ClientIP(req); got != tt.want {
+				t.Errorf("Resolver.ClientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"}); err != nil {
+		t.Errorf("ParseTrustedProxies() error = %v", err)
+	}
+	for _, p := range []string{"10.0.0.0/33", "example.com"} {
+		if _, err := ParseTrustedProxies([]string{p}); err == nil {
+			t.Errorf("ParseTrustedProxies(%v) expected an error", p)
+		}
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	got := ParseForwarded([]string{`for=192.0.2.60;proto=http;by=203.0.113.43, proto=https`, `For="[2001:db8:cafe::17]:4711";host="a,b"`})
+	want := []string{"192.0.2.60", "", "[2001:db8:cafe::17]:4711"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseForwarded() = %q, want %q", got, want)
+	}
+}
+
+func TestResolver_Middleware(t *testing.T) {
+	r, err := NewResolver("X-Real-Ip", []string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Real-Ip", "198.51.100.1")
+	var got string
+	r.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = FromRequest(req)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	if got != "198.51.100.1" {
+		t.Errorf("FromRequest() = %v, want 198.51.100.1", got)
+	}
+	if got := FromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); got != "192.0.2.1" {
+		t.Errorf("FromRequest() without a resolved client IP = %v, want 192.0.2.1", got)
+	}
+}